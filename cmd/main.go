@@ -2,15 +2,20 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"geowarns/internal/handlers"
+	"geowarns/internal/models"
 	repository "geowarns/internal/repository"
 	"geowarns/internal/service"
+	"geowarns/internal/storage"
 	"geowarns/migrations"
 
 	"github.com/gofiber/fiber/v2"
@@ -56,6 +61,32 @@ func main() {
 		{"location_checks", migrations.MigrateLocationCheck},
 		{"incident_stats", migrations.MigrateIncidentStat},
 		{"webhook_tasks", migrations.MigrateWebhookTask},
+		{"list_indexes", migrations.MigrateListIndexes},
+		{"incident_search", migrations.MigrateIncidentSearch},
+		{"incident_translations", migrations.MigrateIncidentTranslations},
+		{"incident_updates", migrations.MigrateIncidentUpdates},
+		{"incident_attachments", migrations.MigrateIncidentAttachments},
+		{"incident_reports", migrations.MigrateIncidentReports},
+		{"incident_votes", migrations.MigrateIncidentVotes},
+		{"webhook_retries", migrations.MigrateWebhookRetries},
+		{"webhook_task_leases", migrations.MigrateWebhookTaskLeases},
+		{"webhook_subscribers", migrations.MigrateWebhookSubscribers},
+		{"webhook_subscription_filters", migrations.MigrateWebhookSubscriptionFilters},
+		{"webhook_dead_letter", migrations.MigrateWebhookDeadLetter},
+		{"webhook_delivery_attempts", migrations.MigrateWebhookDeliveryAttempts},
+		{"user_incident_notifications", migrations.MigrateUserIncidentNotifications},
+		{"combined_alerts", migrations.MigrateCombinedAlerts},
+		{"webhook_digests", migrations.MigrateWebhookDigests},
+		{"incident_lifecycle_events", migrations.MigrateIncidentLifecycleEvents},
+		{"webhook_payload_templates", migrations.MigrateWebhookPayloadTemplates},
+		{"webhook_cloudevents", migrations.MigrateWebhookCloudEvents},
+		{"webhook_circuit_breaker", migrations.MigrateWebhookCircuitBreaker},
+		{"webhook_rate_limits", migrations.MigrateWebhookRateLimits},
+		{"retention", migrations.MigrateRetention},
+		{"delivery_log_signatures", migrations.MigrateDeliveryLogSignatures},
+		{"report_access_tokens", migrations.MigrateReportAccessTokens},
+		{"webhook_task_next_attempt", migrations.MigrateWebhookTaskNextAttempt},
+		{"webhook_task_replay_notes", migrations.MigrateWebhookTaskReplayNotes},
 	}
 
 	var migrationErrs []error
@@ -77,32 +108,92 @@ func main() {
 	locationCheckRepo := repository.NewLocationCheckRepository(dbRepo.DB)
 	webhookTaskRepo := repository.NewWebhookTaskRepository(dbRepo.DB)
 	incidentStatsRepo := repository.NewIncidentStatsRepository(dbRepo.DB)
+	incidentUpdateRepo := repository.NewIncidentUpdateRepository(dbRepo.DB)
+	attachmentRepo := repository.NewAttachmentRepository(dbRepo.DB)
+	reportRepo := repository.NewReportRepository(dbRepo.DB)
+	voteRepo := repository.NewVoteRepository(dbRepo.DB)
+	webhookSubscriberRepo := repository.NewWebhookSubscriberRepository(dbRepo.DB)
+	deliveryAttemptRepo := repository.NewDeliveryAttemptRepository(dbRepo.DB)
+	digestRepo := repository.NewDigestRepository(dbRepo.DB)
+	circuitRepo := repository.NewWebhookCircuitRepository(dbRepo.DB)
+	rateRepo := repository.NewWebhookRateRepository(dbRepo.DB)
 
+	// WEBHOOK_URL задает получателя по умолчанию, остальные управляются через API
 	webhookURL := os.Getenv("WEBHOOK_URL")
 	if webhookURL == "" {
-		zapLogger.Warn("WEBHOOK_URL not set, using default", zap.String("url", "http://localhost:9090/webhook"))
-		webhookURL = "http://localhost:9090/webhook"
+		zapLogger.Info("WEBHOOK_URL not set, webhook subscribers are managed via /api/v1/webhooks/subscribers")
 	}
+	secrets := webhookSecrets(zapLogger)
+
+	// Ограничение размера тела запроса, оно же - максимальный размер вложения
+	bodyLimit := 10 * 1024 * 1024 // 10MB
+	if v := os.Getenv("MAX_BODY_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			zapLogger.Fatal("invalid MAX_BODY_SIZE", zap.String("value", v))
+		}
+		bodyLimit = n
+	}
+
+	blobStore, err := newBlobStore()
+	if err != nil {
+		zapLogger.Fatal("failed to initialize attachment storage", zap.Error(err))
+	}
+	models.AttachmentBaseURL = strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/")
 
 	// Сервисы
 	statsService := service.NewIncidentStatsService(incidentStatsRepo)
-	webhookService := service.NewWebhookService(webhookTaskRepo, webhookURL, zapLogger)
+	webhookSubscriberService := service.NewWebhookSubscriberService(webhookSubscriberRepo, incidentRepo, circuitRepo, zapLogger)
+	attachmentService := service.NewAttachmentService(attachmentRepo, incidentRepo, blobStore, int64(bodyLimit), zapLogger)
+	incidentService := service.NewIncidentService(incidentRepo, webhookSubscriberService, attachmentService)
+	if err := webhookSubscriberService.EnsureDefault(webhookURL, secrets); err != nil {
+		zapLogger.Fatal("failed to create default webhook subscriber", zap.Error(err))
+	}
+	incidentUpdateService := service.NewIncidentUpdateService(incidentUpdateRepo, incidentRepo, webhookTaskRepo, webhookSubscriberService)
+	reportService := service.NewReportService(reportRepo, attachmentService, webhookSubscriberService, reportClusterConfig(zapLogger), zapLogger)
+	voteService := service.NewVoteService(voteRepo, incidentRepo, webhookTaskRepo, webhookSubscriberService, votePolicy(zapLogger))
+	operatorNotifier := service.NewOperatorNotifier(os.Getenv("OPERATOR_WEBHOOK_URL"), secrets, zapLogger)
+	circuitBreaker := service.NewCircuitBreaker(circuitRepo, webhookSubscriberRepo, operatorNotifier, webhookCircuitConfig(zapLogger), zapLogger)
+	webhookService := service.NewWebhookService(webhookTaskRepo, webhookSubscriberRepo, deliveryAttemptRepo, digestRepo, circuitBreaker, rateRepo, webhookURL, secrets, webhookRetryPolicy(zapLogger), webhookWorkerConfig(zapLogger), zapLogger)
+	deadLetterService := service.NewDeadLetterService(webhookTaskRepo, webhookService)
+	webhookQueueService := service.NewWebhookQueueService(webhookTaskRepo)
+	retentionConfig := loadRetentionConfig(zapLogger)
+	retentionService := service.NewRetentionService(repository.NewRetentionRepository(dbRepo.DB), retentionConfig, zapLogger)
+	deliveryLogService := service.NewDeliveryLogService(deliveryAttemptRepo, webhookTaskRepo, webhookSubscriberRepo)
 	locationService := service.NewLocationService(
 		locationCheckRepo,
 		incidentRepo,
 		webhookTaskRepo,
+		webhookSubscriberService,
+		notifyCooldown(zapLogger),
 	)
 
 	// Хендлеры
 	healthHandler := handlers.NewHealthHandler(dbRepo.DB)
 	webhookHandler := handlers.NewWebhookHandler(webhookTaskRepo, zapLogger)
-	mainHandler := handlers.NewLocalRepository(dbRepo, locationService, statsService)
+	subscriberHandler := handlers.NewWebhookSubscriberHandler(webhookSubscriberService, webhookService, zapLogger)
+	deadLetterHandler := handlers.NewDeadLetterHandler(deadLetterService, zapLogger)
+	deliveryLogHandler := handlers.NewDeliveryLogHandler(deliveryLogService, zapLogger)
+	webhookQueueHandler := handlers.NewWebhookQueueHandler(webhookQueueService, zapLogger)
+	retentionHandler := handlers.NewRetentionHandler(retentionService, zapLogger)
+	auth := handlers.NewAuth(apiTokens(zapLogger), zapLogger)
+	incidentUpdateHandler := handlers.NewIncidentUpdateHandler(incidentUpdateService, zapLogger)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, zapLogger)
+	reportHandler := handlers.NewReportHandler(reportService, zapLogger)
+	voteHandler := handlers.NewVoteHandler(voteService, zapLogger)
+	mainHandler := handlers.NewLocalRepository(dbRepo, incidentService, locationService, statsService)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// При остановке дожидаемся выхода обработчика очереди: иначе тик, начавшийся до
+	// cancel(), может запустить отправку уже после webhookService.Wait()
+	pollerDone := make(chan struct{})
+
+	pollInterval := webhookPollInterval(zapLogger)
 	go func() {
-		ticker := time.NewTicker(30 * time.Second)
+		defer close(pollerDone)
+		ticker := time.NewTicker(pollInterval)
 		defer ticker.Stop()
 
 		for {
@@ -118,9 +209,48 @@ func main() {
 		}
 	}()
 
+	// Сводки для получателей в режиме digest: проверка раз в минуту, задача создается
+	// после окончания часа или суток. Как и обработчик очереди, дожидаемся выхода до
+	// закрытия базы.
+	digestDone := make(chan struct{})
+	go func() {
+		defer close(digestDone)
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := webhookService.FlushDigests(); err != nil {
+					zapLogger.Error("Failed to flush webhook digests", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	// Очистка старых задач, журнала доставки и проверок локации по правилам RETENTION_*.
+	// Начатая очистка дорабатывает до конца, и база закрывается только после нее.
+	retentionDone := make(chan struct{})
+	go func() {
+		defer close(retentionDone)
+		ticker := time.NewTicker(retentionConfig.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				retentionService.RunScheduled()
+			}
+		}
+	}()
+
 	app := fiber.New(fiber.Config{
 		AppName:      "GeoWarns API v1.0",
-		BodyLimit:    10 * 1024 * 1024, // 10MB
+		BodyLimit:    bodyLimit,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	})
@@ -137,6 +267,62 @@ func main() {
 	app.Get("/api/v1/system/health", healthHandler.HealthCheck)
 	app.Post("/api/v1/webhooks", webhookHandler.ProcessWebhook)
 	app.Get("/api/v1/webhooks/health", webhookHandler.HealthCheck)
+
+	// Управление доставкой вебхуков (получатели, журнал доставки, очередь, очистка) -
+	// только для администраторов: получатели видят события с user_id и координатами
+	requireAdmin := auth.RequireRole(handlers.RoleAdmin)
+
+	subscribers := app.Group("/api/v1/webhooks/subscribers", requireAdmin)
+	subscribers.Get("/", subscriberHandler.GetSubscribers)
+	subscribers.Post("/", subscriberHandler.CreateSubscriber)
+	subscribers.Post("/test-match", subscriberHandler.TestMatch)
+	subscribers.Post("/preview", subscriberHandler.PreviewPayload)
+	subscribers.Get("/:id", subscriberHandler.GetSubscriber)
+	subscribers.Patch("/:id", subscriberHandler.UpdateSubscriber)
+	subscribers.Delete("/:id", subscriberHandler.DeleteSubscriber)
+	subscribers.Get("/:id/circuit", subscriberHandler.GetCircuit)
+	subscribers.Get("/:id/attempts", deliveryLogHandler.GetSubscriberAttempts)
+
+	admin := app.Group("/api/v1/admin", requireAdmin)
+	admin.Get("/webhook-queue/stats", webhookQueueHandler.GetStats)
+	admin.Get("/webhook-tasks", webhookQueueHandler.GetTasks)
+	admin.Post("/webhook-tasks/bulk", webhookQueueHandler.BulkAction)
+	admin.Get("/webhook-tasks/failed", deadLetterHandler.GetFailedTasks)
+	admin.Post("/webhook-tasks/failed/replay", deadLetterHandler.ReplayFailedTasks)
+	admin.Post("/webhook-tasks/failed/discard", deadLetterHandler.DiscardFailedTasks)
+	admin.Get("/webhook-tasks/:id", deadLetterHandler.GetTask)
+	admin.Delete("/webhook-tasks/:id", webhookQueueHandler.DeleteTask)
+	admin.Get("/webhook-tasks/:id/attempts", deliveryLogHandler.GetTaskAttempts)
+	admin.Post("/webhook-tasks/:id/replay", deadLetterHandler.ReplayTask)
+	admin.Post("/webhook-tasks/:id/discard", deadLetterHandler.DiscardTask)
+	admin.Post("/webhook-tasks/:id/cancel", webhookQueueHandler.CancelTask)
+	admin.Post("/webhook-tasks/:id/retry", webhookQueueHandler.RetryTask)
+	admin.Get("/incidents/:id/webhook-tasks", webhookQueueHandler.GetIncidentTasks)
+	admin.Get("/users/:userId/webhook-tasks", webhookQueueHandler.GetUserTasks)
+	admin.Get("/retention", retentionHandler.GetRetention)
+	admin.Get("/retention/runs", retentionHandler.GetRuns)
+	admin.Post("/retention/run", retentionHandler.RunRetention)
+
+	app.Get("/api/v1/incidents/:id/updates", incidentUpdateHandler.GetUpdates)
+	app.Post("/api/v1/incidents/:id/updates", incidentUpdateHandler.CreateUpdate)
+	app.Get("/api/v1/incidents/:id/attachments", attachmentHandler.GetAttachments)
+	app.Post("/api/v1/incidents/:id/attachments", attachmentHandler.UploadAttachments)
+	app.Get("/api/v1/incidents/:id/attachments/:attachmentId/content", attachmentHandler.GetAttachmentContent)
+	app.Get("/api/v1/incidents/:id/attachments/:attachmentId/thumbnail", attachmentHandler.GetAttachmentThumbnail)
+	app.Delete("/api/v1/incidents/:id/attachments/:attachmentId", attachmentHandler.DeleteAttachment)
+	app.Get("/api/v1/incidents/:id/votes", voteHandler.GetVotes)
+	app.Post("/api/v1/incidents/:id/votes", voteHandler.CastVote)
+	app.Post("/api/v1/reports", reportHandler.SubmitReport)
+	app.Get("/api/v1/reports/:id", auth.Identify(), reportHandler.GetReport)
+	app.Get("/api/v1/reports/:id/photo", auth.Identify(), reportHandler.GetReportPhoto)
+
+	// Одобрение создает инцидент и рассылает его получателям, поэтому модерация - только по токену
+	moderation := app.Group("/api/v1/moderation", auth.RequireRole(handlers.RoleModerator, handlers.RoleAdmin))
+	moderation.Get("/reports", reportHandler.GetModerationQueue)
+	moderation.Get("/reports/:id", reportHandler.GetCluster)
+	moderation.Post("/reports/:id/approve", reportHandler.ApproveCluster)
+	moderation.Post("/reports/:id/merge", reportHandler.MergeCluster)
+	moderation.Post("/reports/:id/reject", reportHandler.RejectCluster)
 	mainHandler.SetupRoutes(app)
 
 	serverAddr := os.Getenv("SERVER_ADDR")
@@ -167,4 +353,360 @@ func main() {
 	if err := app.Listen(serverAddr); err != nil {
 		zapLogger.Error("Server error", zap.Error(err))
 	}
+
+	cancel()
+	<-pollerDone
+	<-digestDone
+	<-retentionDone
+	webhookService.Wait()
+}
+
+// newBlobStore выбирает хранилище вложений по STORAGE_DRIVER: local (по умолчанию) или s3
+func newBlobStore() (storage.BlobStore, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		path := os.Getenv("STORAGE_PATH")
+		if path == "" {
+			path = "./data/attachments"
+		}
+		return storage.NewLocalStore(path)
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER: %s", driver)
+	}
+}
+
+// reportClusterConfig читает REPORT_CLUSTER_RADIUS (метры) и REPORT_CLUSTER_WINDOW (например 1h)
+func reportClusterConfig(logger *zap.Logger) service.ReportClusterConfig {
+	config := service.ReportClusterConfig{
+		RadiusMeters: 300,
+		Window:       time.Hour,
+	}
+
+	if v := os.Getenv("REPORT_CLUSTER_RADIUS"); v != "" {
+		radius, err := strconv.ParseFloat(v, 64)
+		if err != nil || radius <= 0 {
+			logger.Fatal("invalid REPORT_CLUSTER_RADIUS", zap.String("value", v))
+		}
+		config.RadiusMeters = radius
+	}
+	if v := os.Getenv("REPORT_CLUSTER_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window <= 0 {
+			logger.Fatal("invalid REPORT_CLUSTER_WINDOW", zap.String("value", v))
+		}
+		config.Window = window
+	}
+
+	return config
+}
+
+// votePolicy читает VOTE_POLICY_ACTION (flag|deactivate), VOTE_MIN_VOTES,
+// VOTE_DISPUTE_RATIO и VOTE_FAR_WEIGHT
+func votePolicy(logger *zap.Logger) service.VotePolicy {
+	policy := service.DefaultVotePolicy()
+
+	switch v := os.Getenv("VOTE_POLICY_ACTION"); v {
+	case "":
+	case service.VoteActionFlag, service.VoteActionDeactivate:
+		policy.Action = v
+	default:
+		logger.Fatal("invalid VOTE_POLICY_ACTION", zap.String("value", v))
+	}
+
+	if v := os.Getenv("VOTE_MIN_VOTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatal("invalid VOTE_MIN_VOTES", zap.String("value", v))
+		}
+		policy.MinVotes = n
+	}
+	if v := os.Getenv("VOTE_DISPUTE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil || ratio <= 0 || ratio > 1 {
+			logger.Fatal("invalid VOTE_DISPUTE_RATIO", zap.String("value", v))
+		}
+		policy.DisputeRatio = ratio
+	}
+	if v := os.Getenv("VOTE_FAR_WEIGHT"); v != "" {
+		weight, err := strconv.ParseFloat(v, 64)
+		if err != nil || weight < 0 || weight > 1 {
+			logger.Fatal("invalid VOTE_FAR_WEIGHT", zap.String("value", v))
+		}
+		policy.FarVoteWeight = weight
+	}
+
+	return policy
+}
+
+// webhookRetryPolicy читает WEBHOOK_MAX_ATTEMPTS, WEBHOOK_BACKOFF_BASE и WEBHOOK_BACKOFF_MAX
+func webhookRetryPolicy(logger *zap.Logger) service.RetryPolicy {
+	policy := service.DefaultRetryPolicy()
+
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatal("invalid WEBHOOK_MAX_ATTEMPTS", zap.String("value", v))
+		}
+		policy.MaxAttempts = n
+	}
+	if v := os.Getenv("WEBHOOK_BACKOFF_BASE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Fatal("invalid WEBHOOK_BACKOFF_BASE", zap.String("value", v))
+		}
+		policy.BaseDelay = d
+	}
+	if v := os.Getenv("WEBHOOK_BACKOFF_MAX"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Fatal("invalid WEBHOOK_BACKOFF_MAX", zap.String("value", v))
+		}
+		policy.MaxDelay = d
+	}
+
+	return policy
+}
+
+// apiTokens читает ADMIN_TOKENS и MODERATOR_TOKENS - списки "имя:токен" через запятую.
+// Имя записывается как исполнитель действий. Без токенов администратора /api/v1/admin
+// и управление получателями недоступны, без токенов модераторов модерирует только администратор.
+func apiTokens(logger *zap.Logger) map[string]handlers.Principal {
+	tokens := map[string]handlers.Principal{}
+	for _, source := range []struct {
+		env  string
+		role string
+	}{
+		{"ADMIN_TOKENS", handlers.RoleAdmin},
+		{"MODERATOR_TOKENS", handlers.RoleModerator},
+	} {
+		for _, entry := range strings.Split(os.Getenv(source.env), ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			name, token, ok := strings.Cut(entry, ":")
+			name, token = strings.TrimSpace(name), strings.TrimSpace(token)
+			if !ok || name == "" || len(token) < 16 {
+				logger.Fatal("invalid "+source.env+" entry, expected name:token with a token of at least 16 characters",
+					zap.String("name", name))
+			}
+			if _, exists := tokens[token]; exists {
+				logger.Fatal("duplicate API token", zap.String("env", source.env), zap.String("name", name))
+			}
+			tokens[token] = handlers.Principal{Name: name, Role: source.role}
+		}
+	}
+	roles := map[string]int{}
+	for _, principal := range tokens {
+		roles[principal.Role]++
+	}
+	if roles[handlers.RoleAdmin] == 0 {
+		logger.Warn("ADMIN_TOKENS not set, admin API and webhook subscriber management are disabled")
+		if roles[handlers.RoleModerator] == 0 {
+			logger.Warn("MODERATOR_TOKENS not set, report moderation is disabled")
+		}
+	}
+	return tokens
+}
+
+// webhookCircuitConfig читает WEBHOOK_CIRCUIT_THRESHOLD, WEBHOOK_CIRCUIT_OPEN,
+// WEBHOOK_CIRCUIT_OPEN_MAX и WEBHOOK_DISABLE_AFTER (0 - не отключать получателей)
+func webhookCircuitConfig(logger *zap.Logger) service.CircuitConfig {
+	config := service.DefaultCircuitConfig()
+
+	if v := os.Getenv("WEBHOOK_CIRCUIT_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatal("invalid WEBHOOK_CIRCUIT_THRESHOLD", zap.String("value", v))
+		}
+		config.FailureThreshold = n
+	}
+	if v := os.Getenv("WEBHOOK_CIRCUIT_OPEN"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Fatal("invalid WEBHOOK_CIRCUIT_OPEN", zap.String("value", v))
+		}
+		config.OpenTimeout = d
+	}
+	if v := os.Getenv("WEBHOOK_CIRCUIT_OPEN_MAX"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Fatal("invalid WEBHOOK_CIRCUIT_OPEN_MAX", zap.String("value", v))
+		}
+		config.MaxOpenTimeout = d
+	}
+	if config.MaxOpenTimeout < config.OpenTimeout {
+		config.MaxOpenTimeout = config.OpenTimeout
+	}
+	if v := os.Getenv("WEBHOOK_DISABLE_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Fatal("invalid WEBHOOK_DISABLE_AFTER", zap.String("value", v))
+		}
+		config.DisableAfter = d
+	}
+
+	return config
+}
+
+// webhookWorkerConfig читает WEBHOOK_WORKERS и WEBHOOK_LEASE
+func webhookWorkerConfig(logger *zap.Logger) service.WorkerConfig {
+	config := service.DefaultWorkerConfig()
+
+	if v := os.Getenv("WEBHOOK_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatal("invalid WEBHOOK_WORKERS", zap.String("value", v))
+		}
+		config.Workers = n
+	}
+	if v := os.Getenv("WEBHOOK_LEASE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= service.MinWorkerLease {
+			logger.Fatal("invalid WEBHOOK_LEASE, must be longer than "+service.MinWorkerLease.String()+
+				" (request timeout + rate limit wait + margin)", zap.String("value", v))
+		}
+		config.Lease = d
+	}
+
+	return config
+}
+
+// webhookPollInterval читает WEBHOOK_POLL_INTERVAL (по умолчанию 30s)
+func webhookPollInterval(logger *zap.Logger) time.Duration {
+	v := os.Getenv("WEBHOOK_POLL_INTERVAL")
+	if v == "" {
+		return 30 * time.Second
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		logger.Fatal("invalid WEBHOOK_POLL_INTERVAL", zap.String("value", v))
+	}
+	return d
+}
+
+// webhookSecrets читает WEBHOOK_SECRET и WEBHOOK_SECRET_PREVIOUS. На время смены секрета
+// вебхуки подписываются обоими, чтобы получатели успели перейти на новый.
+func webhookSecrets(logger *zap.Logger) []string {
+	var secrets []string
+	if v := os.Getenv("WEBHOOK_SECRET"); v != "" {
+		secrets = append(secrets, v)
+	}
+	if v := os.Getenv("WEBHOOK_SECRET_PREVIOUS"); v != "" {
+		if len(secrets) == 0 {
+			logger.Fatal("WEBHOOK_SECRET_PREVIOUS is set without WEBHOOK_SECRET")
+		}
+		secrets = append(secrets, v)
+	}
+	if len(secrets) == 0 {
+		logger.Warn("WEBHOOK_SECRET not set, outgoing webhooks will not be signed")
+	}
+	return secrets
+}
+
+// loadRetentionConfig читает RETENTION_INTERVAL, RETENTION_BATCH_SIZE, RETENTION_BATCH_PAUSE,
+// RETENTION_DRY_RUN и правила таблиц: RETENTION_<ТАБЛИЦА>_MAX_AGE, _MAX_ROWS и _STATUSES
+// (например RETENTION_WEBHOOK_TASKS_MAX_AGE). Срок журнала доставки по умолчанию берется
+// из WEBHOOK_LOG_RETENTION. Правило без ограничений (0) отключено.
+func loadRetentionConfig(logger *zap.Logger) service.RetentionConfig {
+	config := service.DefaultRetentionConfig()
+
+	if v := os.Getenv("RETENTION_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Fatal("invalid RETENTION_INTERVAL", zap.String("value", v))
+		}
+		config.Interval = d
+	}
+	if v := os.Getenv("RETENTION_BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatal("invalid RETENTION_BATCH_SIZE", zap.String("value", v))
+		}
+		config.BatchSize = n
+	}
+	if v := os.Getenv("RETENTION_BATCH_PAUSE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Fatal("invalid RETENTION_BATCH_PAUSE", zap.String("value", v))
+		}
+		config.BatchPause = d
+	}
+	if v := os.Getenv("RETENTION_DRY_RUN"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Fatal("invalid RETENTION_DRY_RUN", zap.String("value", v))
+		}
+		config.DryRun = dryRun
+	}
+
+	defaultAge := map[string]string{
+		"webhook_tasks":             "720h",
+		"webhook_delivery_attempts": "720h",
+		"location_checks":           "2160h",
+	}
+	if v := os.Getenv("WEBHOOK_LOG_RETENTION"); v != "" {
+		defaultAge["webhook_delivery_attempts"] = v
+	}
+
+	for _, table := range repository.RetentionTables() {
+		prefix := "RETENTION_" + strings.ToUpper(table) + "_"
+		policy := models.RetentionPolicy{Table: table}
+
+		age := os.Getenv(prefix + "MAX_AGE")
+		if age == "" {
+			age = defaultAge[table]
+		}
+		d, err := time.ParseDuration(age)
+		if err != nil || d < 0 {
+			logger.Fatal("invalid "+prefix+"MAX_AGE", zap.String("value", age))
+		}
+		policy.MaxAge = d
+
+		if v := os.Getenv(prefix + "MAX_ROWS"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				logger.Fatal("invalid "+prefix+"MAX_ROWS", zap.String("value", v))
+			}
+			policy.MaxRows = n
+		}
+		if v := os.Getenv(prefix + "STATUSES"); v != "" {
+			for _, status := range strings.Split(v, ",") {
+				if status = strings.TrimSpace(status); status != "" {
+					policy.Statuses = append(policy.Statuses, status)
+				}
+			}
+		}
+		if err := repository.ValidateRetentionPolicy(policy); err != nil {
+			logger.Fatal("invalid "+prefix+"STATUSES", zap.Error(err))
+		}
+
+		if policy.MaxAge > 0 || policy.MaxRows > 0 {
+			config.Policies = append(config.Policies, policy)
+		}
+	}
+
+	return config
+}
+
+// notifyCooldown читает NOTIFY_COOLDOWN - как часто пользователь может получать
+// оповещение об одном и том же инциденте (по умолчанию 1h, 0 отключает ограничение)
+func notifyCooldown(logger *zap.Logger) time.Duration {
+	v := os.Getenv("NOTIFY_COOLDOWN")
+	if v == "" {
+		return time.Hour
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		logger.Fatal("invalid NOTIFY_COOLDOWN", zap.String("value", v))
+	}
+	return d
 }
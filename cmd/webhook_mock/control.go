@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxWaitTimeout = 5 * time.Minute
+
+// registerControl подключает API управления моком под /_mock/
+func (s *Server) registerControl(mux *http.ServeMux) {
+	mux.HandleFunc("GET /_mock/requests", s.listRequests)
+	mux.HandleFunc("GET /_mock/requests/wait", s.waitRequests)
+	mux.HandleFunc("GET /_mock/requests/{id}", s.getRequest)
+	mux.HandleFunc("DELETE /_mock/requests", s.clearRequests)
+
+	mux.HandleFunc("GET /_mock/rules", s.listRules)
+	mux.HandleFunc("POST /_mock/rules", s.addRules)
+	mux.HandleFunc("PUT /_mock/rules", s.replaceRules)
+	mux.HandleFunc("DELETE /_mock/rules", s.deleteRules)
+	mux.HandleFunc("DELETE /_mock/rules/{id}", s.deleteRule)
+
+	mux.HandleFunc("GET /_mock/config", s.getConfig)
+	mux.HandleFunc("PUT /_mock/config", s.putConfig)
+	mux.HandleFunc("POST /_mock/reset", s.reset)
+
+	mux.HandleFunc("/_mock/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]any{"message": "unknown mock endpoint"})
+	})
+}
+
+// parseRequestFilter - фильтр из query: path, method, signature, since_id, limit
+func parseRequestFilter(r *http.Request) (RequestFilter, error) {
+	query := r.URL.Query()
+	filter := RequestFilter{
+		Path:      query.Get("path"),
+		Method:    query.Get("method"),
+		Signature: query.Get("signature"),
+	}
+	if v := query.Get("since_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid since_id")
+		}
+		filter.SinceID = id
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return filter, fmt.Errorf("invalid limit")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func (s *Server) listRequests(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRequestFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	requests := s.recorder.List(filter)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": fmt.Sprintf("%d requests", len(requests)),
+		"data":    requests,
+	})
+}
+
+// waitRequests ждет count подходящих запросов (по умолчанию 1) не дольше timeout
+// (по умолчанию 10s); по истечении отвечает 408 с тем, что успело прийти
+func (s *Server) waitRequests(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRequestFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	count := 1
+	if v := r.URL.Query().Get("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil || count < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"message": "invalid count"})
+			return
+		}
+	}
+	timeout := 10 * time.Second
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 || timeout > maxWaitTimeout {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"message": "invalid timeout"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	requests, ok := s.recorder.Wait(ctx, filter, count)
+	if !ok {
+		writeJSON(w, http.StatusRequestTimeout, map[string]any{
+			"message": fmt.Sprintf("got %d of %d requests", len(requests), count),
+			"data":    requests,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": fmt.Sprintf("%d requests", len(requests)),
+		"data":    requests,
+	})
+}
+
+func (s *Server) getRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": "invalid request id"})
+		return
+	}
+	req, ok := s.recorder.Get(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"message": "request not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"message": "request", "data": req})
+}
+
+func (s *Server) clearRequests(w http.ResponseWriter, r *http.Request) {
+	s.recorder.Clear()
+	writeJSON(w, http.StatusOK, map[string]any{"message": "requests cleared"})
+}
+
+func (s *Server) listRules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"message": "rules", "data": s.script.Rules()})
+}
+
+func (s *Server) addRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := decodeRules(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	added, err := s.script.Add(rules...)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"message": "rules added", "data": added})
+}
+
+func (s *Server) replaceRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := decodeRules(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	added, err := s.script.Replace(rules...)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"message": "rules replaced", "data": added})
+}
+
+func (s *Server) deleteRules(w http.ResponseWriter, r *http.Request) {
+	s.script.Delete(0)
+	writeJSON(w, http.StatusOK, map[string]any{"message": "rules deleted"})
+}
+
+func (s *Server) deleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": "invalid rule id"})
+		return
+	}
+	if !s.script.Delete(id) {
+		writeJSON(w, http.StatusNotFound, map[string]any{"message": "rule not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"message": "rule deleted"})
+}
+
+type mockConfig struct {
+	Verify  string   `json:"verify"`
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+// getConfig не раскрывает секреты, только их количество
+func (s *Server) getConfig(w http.ResponseWriter, r *http.Request) {
+	secrets, verify := s.config()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "config",
+		"data":    map[string]any{"verify": verify, "secrets": len(secrets)},
+	})
+}
+
+// putConfig меняет режим проверки; без secrets остаются текущие секреты
+func (s *Server) putConfig(w http.ResponseWriter, r *http.Request) {
+	var req mockConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": "invalid request body"})
+		return
+	}
+	if req.Secrets == nil {
+		req.Secrets, _ = s.config()
+	}
+	if err := s.configure(req.Secrets, req.Verify); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+	s.getConfig(w, r)
+}
+
+// reset очищает записанные запросы и сценарий
+func (s *Server) reset(w http.ResponseWriter, r *http.Request) {
+	s.recorder.Clear()
+	s.script.Delete(0)
+	writeJSON(w, http.StatusOK, map[string]any{"message": "mock reset"})
+}
+
+// decodeRules принимает одно правило или массив правил
+func decodeRules(r io.Reader) ([]Rule, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+
+	var rules []Rule
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &rules)
+	} else {
+		var rule Rule
+		err = json.Unmarshal(data, &rule)
+		rules = []Rule{rule}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+	return rules, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
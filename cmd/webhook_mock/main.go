@@ -6,30 +6,37 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 )
 
 func main() {
 	port := flag.String("port", "9090", "port to listen on")
+	secret := flag.String("secret", os.Getenv("WEBHOOK_SECRET"), "webhook signing secret (empty disables verification)")
+	previousSecret := flag.String("previous-secret", os.Getenv("WEBHOOK_SECRET_PREVIOUS"), "previous signing secret accepted during rotation")
+	verify := flag.String("verify", os.Getenv("MOCK_VERIFY"), "signature check: off, record or enforce (default: enforce if a secret is set)")
+	rulesFile := flag.String("rules", os.Getenv("MOCK_RULES"), "JSON file with response rules loaded at startup")
+	maxRequests := flag.Int("max-requests", 1000, "how many received requests to keep")
+	hangMax := flag.Duration("hang-max", 5*time.Minute, "how long a \"timeout\" response holds the connection")
 	flag.Parse()
 
-	mux := http.NewServeMux()
+	if *maxRequests < 1 {
+		log.Fatal("max-requests must be positive")
+	}
 
-	// Обработчик вебхуков
-	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
-		var payload map[string]interface{}
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-			http.Error(w, "Bad request", http.StatusBadRequest)
-			return
+	script := NewScript()
+	if *rulesFile != "" {
+		if err := loadRules(script, *rulesFile); err != nil {
+			log.Fatalf("Can't load rules: %v", err)
 		}
+	}
 
-		fmt.Printf("\n[Webhook Received]\nTime: %s\nPayload: %+v\n",
-			time.Now().Format(time.RFC3339),
-			payload)
+	server, err := NewServer(NewRecorder(*maxRequests), script, []string{*secret, *previousSecret}, *verify, *hangMax)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status": "received"}`))
-	})
+	mux := http.NewServeMux()
 
 	// Обработчик проверки состояния
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -37,7 +44,27 @@ func main() {
 		w.Write([]byte(`{"status": "ok"}`))
 	})
 
+	// API управления моком
+	server.registerControl(mux)
+
+	// Вебхуки принимаются на любой путь
+	mux.HandleFunc("/", server.HandleWebhook)
+
 	addr := fmt.Sprintf(":%s", *port)
 	fmt.Printf("Webhook mock server running on %s\n", addr)
 	log.Fatal(http.ListenAndServe(addr, mux))
-}
\ No newline at end of file
+}
+
+// loadRules читает массив правил из файла
+func loadRules(script *Script, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	_, err = script.Add(rules...)
+	return err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Результат проверки подписи запроса
+const (
+	SignatureValid      = "valid"
+	SignatureInvalid    = "invalid"
+	SignatureMissing    = "missing"
+	SignatureNotChecked = "not_checked"
+)
+
+type SignatureCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AppliedResponse - что мок сделал с запросом
+type AppliedResponse struct {
+	RuleID     int    `json:"rule_id,omitempty"`
+	Call       int    `json:"call,omitempty"`
+	Action     string `json:"action"`
+	Status     int    `json:"status,omitempty"`
+	DelayMs    int64  `json:"delay_ms,omitempty"`
+	RetryAfter string `json:"retry_after,omitempty"`
+}
+
+// RecordedRequest - полученный запрос
+type RecordedRequest struct {
+	ID         uint64            `json:"id"`
+	ReceivedAt time.Time         `json:"received_at"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Query      string            `json:"query,omitempty"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	JSON       json.RawMessage   `json:"json,omitempty"`
+	Signature  SignatureCheck    `json:"signature"`
+	Response   AppliedResponse   `json:"response"`
+}
+
+// RequestFilter отбирает записанные запросы
+type RequestFilter struct {
+	Path      string
+	Method    string
+	Signature string
+	SinceID   uint64
+	Limit     int
+}
+
+func (f RequestFilter) match(req *RecordedRequest) bool {
+	return req.ID > f.SinceID &&
+		(f.Path == "" || matchPath(f.Path, req.Path)) &&
+		(f.Method == "" || strings.EqualFold(f.Method, req.Method)) &&
+		(f.Signature == "" || f.Signature == req.Signature.Status)
+}
+
+// Recorder хранит последние max запросов
+type Recorder struct {
+	mu       sync.Mutex
+	requests []RecordedRequest
+	nextID   uint64
+	max      int
+	// changed закрывается и заменяется при каждом новом запросе, чтобы разбудить ожидающих
+	changed chan struct{}
+}
+
+func NewRecorder(max int) *Recorder {
+	return &Recorder{max: max, nextID: 1, changed: make(chan struct{})}
+}
+
+func (r *Recorder) Add(req RecordedRequest) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req.ID = r.nextID
+	r.nextID++
+	r.requests = append(r.requests, req)
+	if len(r.requests) > r.max {
+		r.requests = r.requests[len(r.requests)-r.max:]
+	}
+
+	close(r.changed)
+	r.changed = make(chan struct{})
+	return req.ID
+}
+
+// List возвращает подходящие запросы в порядке получения; при Limit - последние Limit
+func (r *Recorder) List(filter RequestFilter) []RecordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.list(filter)
+}
+
+func (r *Recorder) list(filter RequestFilter) []RecordedRequest {
+	result := []RecordedRequest{}
+	for i := range r.requests {
+		if filter.match(&r.requests[i]) {
+			result = append(result, r.requests[i])
+		}
+	}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[len(result)-filter.Limit:]
+	}
+	return result
+}
+
+func (r *Recorder) Get(id uint64) (RecordedRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, req := range r.requests {
+		if req.ID == id {
+			return req, true
+		}
+	}
+	return RecordedRequest{}, false
+}
+
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = nil
+}
+
+// Wait ждет, пока подходящих запросов станет не меньше count, или отмены ctx
+func (r *Recorder) Wait(ctx context.Context, filter RequestFilter, count int) ([]RecordedRequest, bool) {
+	for {
+		r.mu.Lock()
+		found := r.list(RequestFilter{Path: filter.Path, Method: filter.Method, Signature: filter.Signature, SinceID: filter.SinceID})
+		changed := r.changed
+		r.mu.Unlock()
+
+		if len(found) >= count {
+			return found, true
+		}
+		select {
+		case <-ctx.Done():
+			return found, false
+		case <-changed:
+		}
+	}
+}
+
+func flattenHeaders(header http.Header) map[string]string {
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		result[name] = strings.Join(values, ", ")
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Действия мока в ответ на запрос
+const (
+	// ActionRespond - обычный ответ (с задержкой delay, если задана)
+	ActionRespond = "respond"
+	// ActionTimeout - не отвечать, пока клиент не закроет соединение
+	ActionTimeout = "timeout"
+	// ActionReset - разорвать соединение с RST
+	ActionReset = "reset"
+	// ActionClose - закрыть соединение без ответа
+	ActionClose = "close"
+)
+
+// Duration принимает в JSON строку вида "1.5s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.New(`duration must be a string like "1.5s"`)
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("invalid duration %q", raw)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Response - заготовленный ответ. Пустой Status - 200, пустой Body - {"status": "received"}.
+type Response struct {
+	Action     string            `json:"action,omitempty"`
+	Status     int               `json:"status,omitempty"`
+	Body       *string           `json:"body,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Delay      Duration          `json:"delay,omitempty"`
+	RetryAfter string            `json:"retry_after,omitempty"`
+}
+
+func (r *Response) validate() error {
+	switch r.Action {
+	case "":
+		r.Action = ActionRespond
+	case ActionRespond, ActionTimeout, ActionReset, ActionClose:
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	if r.Status != 0 && (r.Status < 100 || r.Status > 599) {
+		return fmt.Errorf("invalid status %d", r.Status)
+	}
+	return nil
+}
+
+// Rule - сценарий ответов на запросы к Path (точный путь, "/prefix/*" - префикс,
+// пустой - любой) и методу Method (пустой - любой). Подходящие запросы по очереди
+// получают ответы из Responses; когда они закончатся, правило перестает действовать,
+// а при Repeat - начинает сначала.
+type Rule struct {
+	ID        int        `json:"id"`
+	Path      string     `json:"path,omitempty"`
+	Method    string     `json:"method,omitempty"`
+	Responses []Response `json:"responses"`
+	Repeat    bool       `json:"repeat,omitempty"`
+	Calls     int        `json:"calls"`
+}
+
+func (r *Rule) validate() error {
+	if len(r.Responses) == 0 {
+		return errors.New("rule needs at least one response")
+	}
+	for i := range r.Responses {
+		if err := r.Responses[i].validate(); err != nil {
+			return fmt.Errorf("response %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) exhausted() bool {
+	return !r.Repeat && r.Calls >= len(r.Responses)
+}
+
+// Script - правила в порядке добавления; запрос получает ответ первого подходящего
+// незавершенного правила
+type Script struct {
+	mu     sync.Mutex
+	rules  []*Rule
+	nextID int
+}
+
+func NewScript() *Script {
+	return &Script{nextID: 1}
+}
+
+// Add проверяет и добавляет правила в конец
+func (s *Script) Add(rules ...Rule) ([]Rule, error) {
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appendRules(rules), nil
+}
+
+// Replace заменяет все правила новыми
+func (s *Script) Replace(rules ...Rule) ([]Rule, error) {
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = nil
+	return s.appendRules(rules), nil
+}
+
+func validateRules(rules []Rule) error {
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *Script) appendRules(rules []Rule) []Rule {
+	added := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		rule.ID = s.nextID
+		rule.Calls = 0
+		s.nextID++
+		s.rules = append(s.rules, &rule)
+		added = append(added, rule)
+	}
+	return added
+}
+
+func (s *Script) Rules() []Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Rule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		result = append(result, *rule)
+	}
+	return result
+}
+
+// Delete удаляет правило по id, при id = 0 - все правила
+func (s *Script) Delete(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id == 0 {
+		s.rules = nil
+		return true
+	}
+	for i, rule := range s.rules {
+		if rule.ID == id {
+			s.rules = append(s.rules[:i], s.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Next выбирает ответ на запрос. ok = false - ни одно правило не подошло.
+func (s *Script) Next(method, path string) (resp Response, ruleID, call int, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rule := range s.rules {
+		if rule.exhausted() ||
+			(rule.Method != "" && !strings.EqualFold(rule.Method, method)) ||
+			(rule.Path != "" && !matchPath(rule.Path, path)) {
+			continue
+		}
+		resp = rule.Responses[rule.Calls%len(rule.Responses)]
+		rule.Calls++
+		return resp, rule.ID, rule.Calls, true
+	}
+	return Response{}, 0, 0, false
+}
+
+// matchPath сравнивает путь с шаблоном: точное совпадение или префикс для "/prefix/*"
+func matchPath(pattern, path string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return pattern == path
+}
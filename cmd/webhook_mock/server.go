@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"geowarns/pkg/webhooksig"
+)
+
+// Режимы проверки подписи
+const (
+	// VerifyOff - подпись не проверяется
+	VerifyOff = "off"
+	// VerifyRecord - результат проверки только записывается
+	VerifyRecord = "record"
+	// VerifyEnforce - запросы с неверной подписью получают 401
+	VerifyEnforce = "enforce"
+)
+
+const maxBodySize = 10 << 20
+
+var defaultBody = `{"status": "received"}`
+
+// Server принимает вебхуки на любой путь, кроме /health и /_mock/
+type Server struct {
+	recorder *Recorder
+	script   *Script
+	hangMax  time.Duration
+
+	mu      sync.RWMutex
+	secrets []string
+	verify  string
+}
+
+func NewServer(recorder *Recorder, script *Script, secrets []string, verify string, hangMax time.Duration) (*Server, error) {
+	s := &Server{recorder: recorder, script: script, hangMax: hangMax}
+	if err := s.configure(secrets, verify); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// configure задает секреты и режим проверки; пустой режим - enforce при заданных секретах
+func (s *Server) configure(secrets []string, verify string) error {
+	var filtered []string
+	for _, secret := range secrets {
+		if secret != "" {
+			filtered = append(filtered, secret)
+		}
+	}
+
+	switch verify {
+	case "":
+		verify = VerifyOff
+		if len(filtered) > 0 {
+			verify = VerifyEnforce
+		}
+	case VerifyOff:
+	case VerifyRecord, VerifyEnforce:
+		if len(filtered) == 0 {
+			return fmt.Errorf("verify mode %q requires a secret", verify)
+		}
+	default:
+		return fmt.Errorf("unknown verify mode %q", verify)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets = filtered
+	s.verify = verify
+	return nil
+}
+
+func (s *Server) config() ([]string, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.secrets, s.verify
+}
+
+func (s *Server) checkSignature(r *http.Request, body []byte) SignatureCheck {
+	secrets, verify := s.config()
+	if verify == VerifyOff {
+		return SignatureCheck{Status: SignatureNotChecked}
+	}
+
+	err := webhooksig.Verify(r.Header.Get(webhooksig.SignatureHeader), r.Header.Get(webhooksig.TimestampHeader),
+		body, secrets, webhooksig.DefaultTolerance, time.Now())
+	switch {
+	case err == nil:
+		return SignatureCheck{Status: SignatureValid}
+	case errors.Is(err, webhooksig.ErrMissingSignature):
+		return SignatureCheck{Status: SignatureMissing, Error: err.Error()}
+	default:
+		return SignatureCheck{Status: SignatureInvalid, Error: err.Error()}
+	}
+}
+
+// HandleWebhook записывает запрос и отвечает по сценарию
+func (s *Server) HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	req := RecordedRequest{
+		ReceivedAt: time.Now(),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.RawQuery,
+		Headers:    flattenHeaders(r.Header),
+		Body:       string(body),
+		Signature:  s.checkSignature(r, body),
+	}
+	if json.Valid(body) {
+		req.JSON = json.RawMessage(body)
+	}
+
+	resp := s.pickResponse(&req)
+	id := s.recorder.Add(req)
+
+	fmt.Printf("\n[Webhook #%d]\nTime: %s\nPath: %s %s\nSignature: %s\nResponse: %s %d\nPayload: %s\n",
+		id, req.ReceivedAt.Format(time.RFC3339), req.Method, req.Path,
+		req.Signature.Status, req.Response.Action, req.Response.Status, req.Body)
+
+	s.perform(w, r, resp)
+}
+
+// pickResponse выбирает ответ и сохраняет его описание в req.Response
+func (s *Server) pickResponse(req *RecordedRequest) Response {
+	_, verify := s.config()
+	if verify == VerifyEnforce && req.Signature.Status != SignatureValid {
+		body := "Invalid signature\n"
+		resp := Response{Action: ActionRespond, Status: http.StatusUnauthorized, Body: &body}
+		req.Response = AppliedResponse{Action: resp.Action, Status: resp.Status}
+		return resp
+	}
+
+	resp, ruleID, call, ok := s.script.Next(req.Method, req.Path)
+	if !ok {
+		// Без сценария - прежнее поведение: 200 на JSON, 400 на все остальное
+		resp = Response{Action: ActionRespond, Status: http.StatusOK}
+		if req.JSON == nil {
+			body := "Bad request\n"
+			resp.Status, resp.Body = http.StatusBadRequest, &body
+		}
+	}
+	if resp.Action == ActionRespond && resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+
+	req.Response = AppliedResponse{
+		RuleID:     ruleID,
+		Call:       call,
+		Action:     resp.Action,
+		DelayMs:    time.Duration(resp.Delay).Milliseconds(),
+		RetryAfter: resp.RetryAfter,
+	}
+	if resp.Action == ActionRespond {
+		req.Response.Status = resp.Status
+	}
+	return resp
+}
+
+func (s *Server) perform(w http.ResponseWriter, r *http.Request, resp Response) {
+	ctx := r.Context()
+	if resp.Delay > 0 {
+		timer := time.NewTimer(time.Duration(resp.Delay))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+	}
+
+	switch resp.Action {
+	case ActionTimeout:
+		// Держим соединение, пока клиент не сдастся
+		timer := time.NewTimer(s.hangMax)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	case ActionReset, ActionClose:
+		s.dropConnection(w, resp.Action == ActionReset)
+	default:
+		for name, value := range resp.Headers {
+			w.Header().Set(name, value)
+		}
+		if resp.RetryAfter != "" {
+			w.Header().Set("Retry-After", resp.RetryAfter)
+		}
+		body := defaultBody
+		if resp.Body != nil {
+			body = *resp.Body
+		}
+		if w.Header().Get("Content-Type") == "" && json.Valid([]byte(body)) {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(resp.Status)
+		w.Write([]byte(body))
+	}
+}
+
+// dropConnection закрывает соединение без ответа; при reset клиент получает RST вместо FIN
+func (s *Server) dropConnection(w http.ResponseWriter, reset bool) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok && reset {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
@@ -0,0 +1,54 @@
+package geo
+
+import "math"
+
+const earthRadiusMeters = 6371000.0
+
+// metersPerDegreeLat - длина одного градуса широты
+const metersPerDegreeLat = 111320.0
+
+// DistanceMeters возвращает расстояние между двумя точками по формуле гаверсинусов
+func DistanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// DegreesAround возвращает полуширину квадрата (в градусах широты и долготы),
+// описанного вокруг круга радиуса meters с центром на широте lat.
+// Используется для грубой предварительной фильтрации в SQL.
+func DegreesAround(lat, meters float64) (dLat, dLng float64) {
+	dLat = meters / metersPerDegreeLat
+	cos := math.Cos(toRadians(lat))
+	if cos < 0.01 {
+		cos = 0.01
+	}
+	dLng = meters / (metersPerDegreeLat * cos)
+	return dLat, dLng
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Point - точка в градусах
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// PointInPolygon проверяет, лежит ли точка внутри многоугольника (метод трассировки луча).
+// Многоугольник задается вершинами по порядку, замыкать его не нужно.
+func PointInPolygon(lat, lng float64, polygon []Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lng < (b.Lng-a.Lng)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
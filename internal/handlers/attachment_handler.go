@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"geowarns/internal/models"
+	"geowarns/internal/service"
+	"geowarns/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AttachmentHandler struct {
+	attachmentService *service.AttachmentService
+	logger            *zap.Logger
+}
+
+func NewAttachmentHandler(attachmentService *service.AttachmentService, logger *zap.Logger) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+		logger:            logger,
+	}
+}
+
+// UploadAttachments принимает multipart/form-data с одним или несколькими полями "file"
+func (h *AttachmentHandler) UploadAttachments(c *fiber.Ctx) error {
+	incidentID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse multipart form",
+			"error":   err.Error(),
+		})
+	}
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "file is required",
+		})
+	}
+
+	// Загрузка - все или ничего: при ошибке уже сохраненные файлы удаляются
+	attachments := make([]*models.IncidentAttachment, 0, len(files))
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			h.attachmentService.Discard(c.UserContext(), attachments)
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "can't read uploaded file",
+				"error":   err.Error(),
+			})
+		}
+
+		attachment, err := h.attachmentService.Upload(c.UserContext(), uint(incidentID), header.Filename, file)
+		file.Close()
+		if err != nil {
+			h.attachmentService.Discard(c.UserContext(), attachments)
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				return c.Status(http.StatusNotFound).JSON(fiber.Map{
+					"message": "incident not found",
+				})
+			case errors.Is(err, service.ErrAttachmentTooLarge):
+				return c.Status(http.StatusRequestEntityTooLarge).JSON(fiber.Map{
+					"message": err.Error(),
+					"file":    header.Filename,
+				})
+			case errors.Is(err, service.ErrUnsupportedAttachment), errors.Is(err, service.ErrAttachmentEmpty):
+				return c.Status(http.StatusUnsupportedMediaType).JSON(fiber.Map{
+					"message": err.Error(),
+					"file":    header.Filename,
+				})
+			}
+			h.logger.Error("Failed to upload attachment", zap.Uint64("incident_id", incidentID), zap.Error(err))
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"message": "can't upload attachment",
+			})
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "attachments were uploaded successfully",
+		"data":    attachments,
+	})
+}
+
+func (h *AttachmentHandler) GetAttachments(c *fiber.Ctx) error {
+	incidentID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	attachments, err := h.attachmentService.GetAttachments(uint(incidentID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "incident not found",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get attachments",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "attachments list",
+		"data":    attachments,
+	})
+}
+
+func (h *AttachmentHandler) GetAttachmentContent(c *fiber.Ctx) error {
+	return h.sendContent(c, false)
+}
+
+func (h *AttachmentHandler) GetAttachmentThumbnail(c *fiber.Ctx) error {
+	return h.sendContent(c, true)
+}
+
+func (h *AttachmentHandler) sendContent(c *fiber.Ctx, thumbnail bool) error {
+	incidentID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+	attachmentID, err := strconv.ParseUint(c.Params("attachmentId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid attachment ID",
+		})
+	}
+
+	attachment, body, err := h.attachmentService.OpenContent(c.UserContext(), uint(incidentID), uint(attachmentID), thumbnail)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, storage.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "attachment not found",
+			})
+		}
+		h.logger.Error("Failed to read attachment", zap.Uint64("attachment_id", attachmentID), zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't read attachment",
+		})
+	}
+
+	contentType := attachment.ContentType
+	if thumbnail {
+		contentType = "image/jpeg"
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", attachment.FileName))
+	c.Set("X-Content-Type-Options", "nosniff")
+
+	// fasthttp сам закроет body после отправки
+	return c.SendStream(body)
+}
+
+func (h *AttachmentHandler) DeleteAttachment(c *fiber.Ctx) error {
+	incidentID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+	attachmentID, err := strconv.ParseUint(c.Params("attachmentId"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid attachment ID",
+		})
+	}
+
+	if err := h.attachmentService.Delete(c.UserContext(), uint(incidentID), uint(attachmentID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "attachment not found",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't delete attachment",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "attachment deleted successfully",
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+
+	"geowarns/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+const principalKey = "principal"
+
+// Principal - владелец API-токена
+type Principal struct {
+	Name string
+	Role string
+}
+
+// Auth проверяет API-токены из заголовка Authorization: Bearer <token>
+type Auth struct {
+	tokens map[[sha256.Size]byte]Principal
+	logger *zap.Logger
+}
+
+// NewAuth принимает токены и их владельцев. Токены хранятся в виде SHA-256,
+// сравнение выполняется за постоянное время.
+func NewAuth(tokens map[string]Principal, logger *zap.Logger) *Auth {
+	hashed := make(map[[sha256.Size]byte]Principal, len(tokens))
+	for token, principal := range tokens {
+		hashed[sha256.Sum256([]byte(token))] = principal
+	}
+	return &Auth{tokens: hashed, logger: logger}
+}
+
+// RequireRole пропускает только запросы с токеном владельца с одной из ролей roles
+func (a *Auth) RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if len(a.tokens) == 0 {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"message": "no API tokens configured",
+			})
+		}
+
+		token, ok := bearerToken(c)
+		if !ok {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"message": "missing bearer token",
+			})
+		}
+
+		principal, ok := a.lookup(token)
+		if !ok {
+			a.logger.Warn("Invalid API token", zap.String("path", c.Path()), zap.String("ip", c.IP()))
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"message": "invalid token",
+			})
+		}
+		if !slices.Contains(roles, principal.Role) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"message": "insufficient role",
+			})
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+// Identify запоминает владельца токена, если он передан, но пропускает и запросы
+// без токена: доступ решает обработчик (см. hasRole)
+func (a *Auth) Identify() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := bearerToken(c)
+		if !ok {
+			return c.Next()
+		}
+
+		principal, ok := a.lookup(token)
+		if !ok {
+			a.logger.Warn("Invalid API token", zap.String("path", c.Path()), zap.String("ip", c.IP()))
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"message": "invalid token",
+			})
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	token = strings.TrimSpace(token)
+	return token, ok && token != ""
+}
+
+func (a *Auth) lookup(token string) (Principal, bool) {
+	sum := sha256.Sum256([]byte(token))
+	var found Principal
+	var ok bool
+	for hash, principal := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			found, ok = principal, true
+		}
+	}
+	return found, ok
+}
+
+// hasRole - передан ли токен владельца с одной из ролей roles
+func hasRole(c *fiber.Ctx, roles ...string) bool {
+	principal, ok := c.Locals(principalKey).(Principal)
+	return ok && slices.Contains(roles, principal.Role)
+}
+
+// actorName возвращает владельца токена запроса. Исполнитель в журналах всегда берется
+// из токена, а не из тела запроса, чтобы его нельзя было подменить.
+func actorName(c *fiber.Ctx) *string {
+	if principal, ok := c.Locals(principalKey).(Principal); ok {
+		return &principal.Name
+	}
+	return nil
+}
+
+// taskActor - исполнитель действия над задачами с необязательным комментарием note
+func taskActor(c *fiber.Ctx, note *string) models.TaskActor {
+	if note != nil {
+		trimmed := strings.TrimSpace(*note)
+		note = &trimmed
+		if trimmed == "" {
+			note = nil
+		}
+	}
+	return models.TaskActor{Name: actorName(c), Note: note}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"geowarns/internal/models"
+	database "geowarns/internal/repository"
+	"geowarns/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DeadLetterHandler - администрирование задач вебхуков, доставка которых не удалась
+type DeadLetterHandler struct {
+	deadLetterService *service.DeadLetterService
+	logger            *zap.Logger
+}
+
+func NewDeadLetterHandler(deadLetterService *service.DeadLetterService, logger *zap.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetterService: deadLetterService,
+		logger:            logger,
+	}
+}
+
+func (h *DeadLetterHandler) GetFailedTasks(c *fiber.Ctx) error {
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	filter, err := parseWebhookTaskFilter(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	tasks, pageInfo, err := h.deadLetterService.ListFailed(filter, page)
+	if err != nil {
+		if isPageRequestError(err) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid pagination parameters",
+				"error":   err.Error(),
+			})
+		}
+		h.logger.Error("Failed to list failed webhook tasks", zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get failed tasks",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "failed webhook tasks",
+		"data":       tasks,
+		"pagination": pageInfo,
+	})
+}
+
+func (h *DeadLetterHandler) GetTask(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	task, err := h.deadLetterService.GetTask(uint(id))
+	if err != nil {
+		return h.handleError(c, err, "can't get webhook task")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook task",
+		"data":    task,
+	})
+}
+
+func (h *DeadLetterHandler) ReplayTask(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	var req models.DeadLetterActionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "can't parse request",
+			})
+		}
+	}
+
+	if err := h.deadLetterService.Replay(uint(id), taskActor(c, req.Note)); err != nil {
+		return h.handleError(c, err, "can't replay webhook task")
+	}
+
+	h.logger.Info("Webhook task replayed", zap.Uint64("task_id", id))
+	return c.JSON(fiber.Map{
+		"message": "webhook task replayed",
+	})
+}
+
+func (h *DeadLetterHandler) ReplayFailedTasks(c *fiber.Ctx) error {
+	var req models.DeadLetterActionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "can't parse request",
+			})
+		}
+	}
+
+	replayed, err := h.deadLetterService.ReplayMatching(req.WebhookTaskFilter, taskActor(c, req.Note))
+	if err != nil {
+		return h.handleError(c, err, "can't replay webhook tasks")
+	}
+
+	h.logger.Info("Failed webhook tasks replayed", zap.Int64("count", replayed))
+	return c.JSON(fiber.Map{
+		"message": "failed webhook tasks replayed",
+		"data": fiber.Map{
+			"replayed": replayed,
+		},
+	})
+}
+
+func (h *DeadLetterHandler) DiscardTask(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	if err := h.deadLetterService.Discard(uint(id)); err != nil {
+		return h.handleError(c, err, "can't discard webhook task")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook task discarded",
+	})
+}
+
+func (h *DeadLetterHandler) DiscardFailedTasks(c *fiber.Ctx) error {
+	var req models.DeadLetterActionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "can't parse request",
+			})
+		}
+	}
+
+	discarded, err := h.deadLetterService.DiscardMatching(req.WebhookTaskFilter)
+	if err != nil {
+		return h.handleError(c, err, "can't discard webhook tasks")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "failed webhook tasks discarded",
+		"data": fiber.Map{
+			"discarded": discarded,
+		},
+	})
+}
+
+func (h *DeadLetterHandler) handleError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "webhook task not found",
+		})
+	case errors.Is(err, database.ErrTaskNotFailed):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	h.logger.Error("Dead letter request failed", zap.Error(err))
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"message": message,
+		"error":   err.Error(),
+	})
+}
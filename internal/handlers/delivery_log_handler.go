@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"geowarns/internal/models"
+	"geowarns/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type DeliveryLogHandler struct {
+	deliveryLogService *service.DeliveryLogService
+	logger             *zap.Logger
+}
+
+func NewDeliveryLogHandler(deliveryLogService *service.DeliveryLogService, logger *zap.Logger) *DeliveryLogHandler {
+	return &DeliveryLogHandler{
+		deliveryLogService: deliveryLogService,
+		logger:             logger,
+	}
+}
+
+func (h *DeliveryLogHandler) GetTaskAttempts(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	attempts, err := h.deliveryLogService.GetTaskAttempts(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "webhook task not found",
+			})
+		}
+		h.logger.Error("Failed to get delivery attempts", zap.Uint64("task_id", id), zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get delivery attempts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "delivery attempts",
+		"data":    attempts,
+	})
+}
+
+func (h *DeliveryLogHandler) GetSubscriberAttempts(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	var filter models.DeliveryAttemptFilter
+	if filter.From, err = parseOptionalTime(c, "from"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	if filter.To, err = parseOptionalTime(c, "to"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	attempts, pageInfo, err := h.deliveryLogService.GetSubscriberAttempts(uint(id), filter, page)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "webhook subscriber not found",
+			})
+		case isPageRequestError(err):
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid pagination parameters",
+				"error":   err.Error(),
+			})
+		}
+		h.logger.Error("Failed to get delivery attempts", zap.Uint64("subscriber_id", id), zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get delivery attempts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "delivery attempts",
+		"data":       attempts,
+		"pagination": pageInfo,
+	})
+}
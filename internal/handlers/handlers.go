@@ -1,29 +1,35 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"geowarns/internal/models"
 	database "geowarns/internal/repository"
 	"geowarns/internal/service"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type LocalRepository struct {
 	db              *database.Repository
+	incidentService *service.IncidentService
 	locationService *service.LocationService
 	statsService    *service.IncidentStatsService
 }
 
 func NewLocalRepository(
 	db *database.Repository,
+	incidentService *service.IncidentService,
 	locationService *service.LocationService,
 	statsService *service.IncidentStatsService,
 ) *LocalRepository {
 	return &LocalRepository{
 		db:              db,
+		incidentService: incidentService,
 		locationService: locationService,
 		statsService:    statsService,
 	}
@@ -45,13 +51,22 @@ func (r *LocalRepository) CreateIncident(c *fiber.Ctx) error {
 		Longitude:   req.Longitude,
 		Radius:      req.Radius,
 		IsActive:    true,
+		Category:    req.Category,
+		Severity:    req.Severity,
+		Language:    req.Language,
 	}
 
 	if req.IsActive != nil {
 		incident.IsActive = *req.IsActive
 	}
 
-	if err := r.db.DB.Create(incident).Error; err != nil {
+	if err := r.incidentService.CreateIncident(incident, req.Translations); err != nil {
+		if errors.Is(err, service.ErrInvalidLanguage) || errors.Is(err, service.ErrInvalidCategory) || errors.Is(err, service.ErrInvalidSeverity) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid incident",
+				"error":   err.Error(),
+			})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "can't create incident",
 			"error":   err.Error(),
@@ -66,19 +81,94 @@ func (r *LocalRepository) CreateIncident(c *fiber.Ctx) error {
 
 
 func (r *LocalRepository) GetIncidentList(c *fiber.Ctx) error {
-	var incidents []models.Incident
-	if err := r.db.DB.Find(&incidents).Error; err != nil {
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	filter, err := parseIncidentFilter(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	incidents, pageInfo, err := r.incidentService.ListIncidents(filter, page, preferredLanguages(c))
+	if err != nil {
+		if isPageRequestError(err) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid pagination parameters",
+				"error":   err.Error(),
+			})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "can't get incidents",
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "incidents list",
-		"data":    incidents,
+		"message":    "incidents list",
+		"data":       incidents,
+		"pagination": pageInfo,
 	})
 }
 
+func (r *LocalRepository) SearchIncidents(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "q parameter is required",
+		})
+	}
+
+	filter, err := parseIncidentFilter(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid offset parameter",
+		})
+	}
+
+	results, total, err := r.incidentService.SearchIncidents(models.IncidentSearchRequest{
+		Query:     query,
+		Filter:    filter,
+		Limit:     page.Limit,
+		Offset:    offset,
+		WithTotal: page.WithTotal,
+	}, preferredLanguages(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't search incidents",
+			"error":   err.Error(),
+		})
+	}
+
+	response := fiber.Map{
+		"message": "incidents search results",
+		"data":    results,
+	}
+	if page.WithTotal {
+		response["total"] = total
+	}
+
+	return c.JSON(response)
+}
+
 func (r *LocalRepository) GetIncidentByID(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
 	if err != nil {
@@ -87,8 +177,8 @@ func (r *LocalRepository) GetIncidentByID(c *fiber.Ctx) error {
 		})
 	}
 
-	var incident models.Incident
-	if err := r.db.DB.First(&incident, id).Error; err != nil {
+	incident, err := r.incidentService.GetIncident(uint(id), preferredLanguages(c))
+	if err != nil {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{
 			"message": "incident not found",
 		})
@@ -115,8 +205,8 @@ func (r *LocalRepository) UpdateIncidentByID(c *fiber.Ctx) error {
 		})
 	}
 
-	var incident models.Incident
-	if err := r.db.DB.First(&incident, id).Error; err != nil {
+	incident, err := r.incidentService.GetIncident(uint(id), nil)
+	if err != nil {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{
 			"message": "incident not found",
 		})
@@ -140,8 +230,26 @@ func (r *LocalRepository) UpdateIncidentByID(c *fiber.Ctx) error {
 	if req.IsActive != nil {
 		incident.IsActive = *req.IsActive
 	}
+	if req.Category != "" {
+		incident.Category = req.Category
+	}
+	if req.Severity != "" {
+		incident.Severity = req.Severity
+	}
+	if req.NeedsReview != nil {
+		incident.NeedsReview = *req.NeedsReview
+	}
+	if req.Language != "" {
+		incident.Language = req.Language
+	}
 
-	if err := r.db.DB.Save(&incident).Error; err != nil {
+	if err := r.incidentService.UpdateIncident(incident, req.Translations); err != nil {
+		if errors.Is(err, service.ErrInvalidLanguage) || errors.Is(err, service.ErrInvalidCategory) || errors.Is(err, service.ErrInvalidSeverity) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid incident",
+				"error":   err.Error(),
+			})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "can't update incident",
 		})
@@ -161,14 +269,12 @@ func (r *LocalRepository) DeleteIncidentByID(c *fiber.Ctx) error {
 		})
 	}
 
-	var incident models.Incident
-	if err := r.db.DB.First(&incident, id).Error; err != nil {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"message": "incident not found",
-		})
-	}
-
-	if err := r.db.DB.Delete(&incident).Error; err != nil {
+	if err := r.incidentService.DeleteIncident(c.UserContext(), uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "incident not found",
+			})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "can't delete incident",
 		})
@@ -193,6 +299,12 @@ func (r *LocalRepository) CheckLocation(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.Language == "" {
+		if langs := preferredLanguages(c); len(langs) > 0 {
+			req.Language = langs[0]
+		}
+	}
+
 	check, incidents, err := r.locationService.CheckLocation(&req)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
@@ -210,16 +322,37 @@ func (r *LocalRepository) CheckLocation(c *fiber.Ctx) error {
 }
 
 func (r *LocalRepository) GetLocationChecks(c *fiber.Ctx) error {
-	checks, err := r.locationService.GetLocationChecks()
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	filter, err := parseLocationCheckFilter(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	checks, pageInfo, err := r.locationService.GetLocationChecks(filter, page)
 	if err != nil {
+		if isPageRequestError(err) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid pagination parameters",
+				"error":   err.Error(),
+			})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"message": "can't get location checks",
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "location checks list",
-		"data":    checks,
+		"message":    "location checks list",
+		"data":       checks,
+		"pagination": pageInfo,
 	})
 }
 
@@ -272,6 +405,7 @@ func (r *LocalRepository) SetupRoutes(app *fiber.App) {
 	// Эндпоинты для инцидентов
 	incidentAPI := app.Group("/api/v1/incidents")
 	incidentAPI.Get("/stats", r.GetIncidentStats)
+	incidentAPI.Get("/search", r.SearchIncidents)
 	incidentAPI.Post("/", r.CreateIncident)
 	incidentAPI.Get("/", r.GetIncidentList)
 	incidentAPI.Get("/:id", r.GetIncidentByID)
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"geowarns/internal/models"
+	"geowarns/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type IncidentUpdateHandler struct {
+	updateService *service.IncidentUpdateService
+	logger        *zap.Logger
+}
+
+func NewIncidentUpdateHandler(updateService *service.IncidentUpdateService, logger *zap.Logger) *IncidentUpdateHandler {
+	return &IncidentUpdateHandler{
+		updateService: updateService,
+		logger:        logger,
+	}
+}
+
+func (h *IncidentUpdateHandler) CreateUpdate(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	var req models.IncidentUpdateCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+			"error":   err.Error(),
+		})
+	}
+
+	update, err := h.updateService.PublishUpdate(uint(id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptyUpdateMessage):
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "incident not found",
+			})
+		}
+		h.logger.Error("Failed to publish incident update", zap.Uint64("incident_id", id), zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't create incident update",
+		})
+	}
+
+	h.logger.Info("Incident update published",
+		zap.Uint64("incident_id", id),
+		zap.Uint("update_id", update.ID),
+		zap.Int("notified_users", update.NotifiedUsers))
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "incident update was created successfully",
+		"data":    update,
+	})
+}
+
+func (h *IncidentUpdateHandler) GetUpdates(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	updates, err := h.updateService.GetUpdates(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "incident not found",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get incident updates",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "incident updates list",
+		"data":    updates,
+	})
+}
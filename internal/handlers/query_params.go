@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"geowarns/internal/locale"
+	"geowarns/internal/models"
+	database "geowarns/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parsePageRequest читает параметры cursor, limit, sort_by, sort_order и with_total
+func parsePageRequest(c *fiber.Ctx) (models.PageRequest, error) {
+	page := models.PageRequest{
+		Cursor:    c.Query("cursor"),
+		SortBy:    c.Query("sort_by"),
+		SortOrder: strings.ToLower(c.Query("sort_order")),
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("invalid limit parameter")
+		}
+		page.Limit = limit
+	}
+
+	withTotal, err := parseOptionalBool(c, "with_total")
+	if err != nil {
+		return page, err
+	}
+	if withTotal != nil {
+		page.WithTotal = *withTotal
+	}
+
+	return page, nil
+}
+
+func parseOptionalBool(c *fiber.Ctx, key string) (*bool, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter", key)
+	}
+	return &value, nil
+}
+
+// parseOptionalTime принимает время в формате RFC3339
+func parseOptionalTime(c *fiber.Ctx, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter, expected RFC3339", key)
+	}
+	return &value, nil
+}
+
+// parseBBox разбирает параметр bbox в формате min_lng,min_lat,max_lng,max_lat
+func parseBBox(c *fiber.Ctx) (*models.BBox, error) {
+	raw := c.Query("bbox")
+	if raw == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid bbox parameter, expected min_lng,min_lat,max_lng,max_lat")
+	}
+
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bbox parameter")
+		}
+		values[i] = v
+	}
+
+	bbox := &models.BBox{
+		MinLng: values[0],
+		MinLat: values[1],
+		MaxLng: values[2],
+		MaxLat: values[3],
+	}
+	if bbox.MinLat > bbox.MaxLat || bbox.MinLng > bbox.MaxLng ||
+		bbox.MinLat < -90 || bbox.MaxLat > 90 || bbox.MinLng < -180 || bbox.MaxLng > 180 {
+		return nil, fmt.Errorf("invalid bbox parameter")
+	}
+
+	return bbox, nil
+}
+
+func parseIncidentFilter(c *fiber.Ctx) (models.IncidentFilter, error) {
+	filter := models.IncidentFilter{
+		Category: c.Query("category"),
+	}
+	var err error
+
+	if filter.IsActive, err = parseOptionalBool(c, "is_active"); err != nil {
+		return filter, err
+	}
+	if filter.NeedsReview, err = parseOptionalBool(c, "needs_review"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = parseOptionalTime(c, "created_from"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseOptionalTime(c, "created_to"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedFrom, err = parseOptionalTime(c, "updated_from"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedTo, err = parseOptionalTime(c, "updated_to"); err != nil {
+		return filter, err
+	}
+	if filter.BBox, err = parseBBox(c); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseLocationCheckFilter(c *fiber.Ctx) (models.LocationCheckFilter, error) {
+	filter := models.LocationCheckFilter{
+		UserID: c.Query("user_id"),
+	}
+	var err error
+
+	if filter.From, err = parseOptionalTime(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseOptionalTime(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.BBox, err = parseBBox(c); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseOptionalUint(c *fiber.Ctx, key string) (*uint, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter", key)
+	}
+	v := uint(value)
+	return &v, nil
+}
+
+func parseWebhookTaskFilter(c *fiber.Ctx) (models.WebhookTaskFilter, error) {
+	var filter models.WebhookTaskFilter
+	var err error
+
+	if filter.IncidentID, err = parseOptionalUint(c, "incident_id"); err != nil {
+		return filter, err
+	}
+	if filter.SubscriberID, err = parseOptionalUint(c, "subscriber_id"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = parseOptionalTime(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseOptionalTime(c, "to"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func isPageRequestError(err error) bool {
+	return errors.Is(err, database.ErrInvalidCursor) || errors.Is(err, database.ErrInvalidSort)
+}
+
+// preferredLanguages возвращает языки клиента: параметр lang имеет приоритет
+// над заголовком Accept-Language
+func preferredLanguages(c *fiber.Ctx) []string {
+	c.Vary(fiber.HeaderAcceptLanguage)
+	if lang := c.Query("lang"); lang != "" {
+		return locale.ParseAcceptLanguage(lang)
+	}
+	return locale.ParseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage))
+}
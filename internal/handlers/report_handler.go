@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"geowarns/internal/models"
+	database "geowarns/internal/repository"
+	"geowarns/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ReportHandler struct {
+	reportService *service.ReportService
+	logger        *zap.Logger
+}
+
+func NewReportHandler(reportService *service.ReportService, logger *zap.Logger) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+		logger:        logger,
+	}
+}
+
+// SubmitReport принимает JSON или multipart/form-data (с необязательным файлом "photo")
+func (h *ReportHandler) SubmitReport(c *fiber.Ctx) error {
+	var req models.IncidentReportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+			"error":   err.Error(),
+		})
+	}
+
+	var photo io.Reader
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		if header, err := c.FormFile("photo"); err == nil {
+			file, err := header.Open()
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+					"message": "can't read photo",
+					"error":   err.Error(),
+				})
+			}
+			defer file.Close()
+			photo = file
+		}
+	}
+
+	report, cluster, err := h.reportService.SubmitReport(c.UserContext(), &req, photo)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidReport):
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		case errors.Is(err, service.ErrAttachmentTooLarge):
+			return c.Status(http.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		case errors.Is(err, service.ErrUnsupportedAttachment), errors.Is(err, service.ErrAttachmentEmpty):
+			return c.Status(http.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		}
+		h.logger.Error("Failed to submit report", zap.String("user_id", req.UserID), zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't submit report",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "report was submitted for moderation",
+		"data": fiber.Map{
+			"report":       report,
+			"report_count": cluster.ReportCount,
+		},
+	})
+}
+
+// ReportTokenHeader - заголовок с токеном автора сообщения, выданным при отправке
+const ReportTokenHeader = "X-Report-Token"
+
+// GetReport - сообщение читают модераторы и его автор (по токену из ответа на отправку)
+func (h *ReportHandler) GetReport(c *fiber.Ctx) error {
+	report, status, message := h.readableReport(c)
+	if report == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"message": message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "report found",
+		"data":    report,
+	})
+}
+
+func (h *ReportHandler) GetReportPhoto(c *fiber.Ctx) error {
+	report, status, message := h.readableReport(c)
+	if report == nil {
+		return c.Status(status).JSON(fiber.Map{
+			"message": message,
+		})
+	}
+
+	body, err := h.reportService.OpenReportPhoto(c.UserContext(), report)
+	if err != nil || body == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "photo not found",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, *report.PhotoType)
+	c.Set("X-Content-Type-Options", "nosniff")
+	return c.SendStream(body)
+}
+
+// readableReport возвращает сообщение, если его может читать автор запроса, иначе
+// статус и текст ошибки. Чужое сообщение неотличимо от несуществующего.
+func (h *ReportHandler) readableReport(c *fiber.Ctx) (*models.IncidentReport, int, string) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, http.StatusBadRequest, "invalid ID"
+	}
+
+	moderator := hasRole(c, RoleModerator, RoleAdmin)
+	token := c.Get(ReportTokenHeader)
+	if !moderator && token == "" {
+		return nil, http.StatusUnauthorized, "report token or moderator token required"
+	}
+
+	report, err := h.reportService.GetReport(uint(id))
+	if err != nil || (!moderator && !h.reportService.CheckReportToken(report, token)) {
+		return nil, http.StatusNotFound, "report not found"
+	}
+	return report, http.StatusOK, ""
+}
+
+// GetModerationQueue - список кластеров сообщений (по умолчанию ожидающих модерации)
+func (h *ReportHandler) GetModerationQueue(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", models.DefaultPageLimit)
+	offset := c.QueryInt("offset", 0)
+
+	clusters, total, err := h.reportService.ListClusters(c.Query("status"), limit, offset)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get moderation queue",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "moderation queue",
+		"data":    clusters,
+		"total":   total,
+	})
+}
+
+func (h *ReportHandler) GetCluster(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	cluster, err := h.reportService.GetCluster(uint(id))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "report cluster not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "report cluster found",
+		"data":    cluster,
+	})
+}
+
+func (h *ReportHandler) ApproveCluster(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	var req models.ReportApproveRequest
+	if err := c.BodyParser(&req); err != nil && len(c.Body()) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+
+	req.Moderator = actorName(c)
+	cluster, err := h.reportService.Approve(c.UserContext(), uint(id), &req)
+	if err != nil {
+		return h.moderationError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "reports approved, incident created",
+		"data":    cluster,
+	})
+}
+
+func (h *ReportHandler) MergeCluster(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	var req models.ReportMergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+
+	req.Moderator = actorName(c)
+	cluster, err := h.reportService.Merge(c.UserContext(), uint(id), &req)
+	if err != nil {
+		return h.moderationError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "reports merged into incident",
+		"data":    cluster,
+	})
+}
+
+func (h *ReportHandler) RejectCluster(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	var req models.ReportRejectRequest
+	if err := c.BodyParser(&req); err != nil && len(c.Body()) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+
+	req.Moderator = actorName(c)
+	cluster, err := h.reportService.Reject(uint(id), &req)
+	if err != nil {
+		return h.moderationError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "reports rejected",
+		"data":    cluster,
+	})
+}
+
+func (h *ReportHandler) moderationError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "report cluster or incident not found",
+		})
+	case errors.Is(err, database.ErrClusterNotPending):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidReport), errors.Is(err, service.ErrInvalidLanguage), errors.Is(err, service.ErrInvalidSeverity):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	h.logger.Error("Moderation action failed", zap.Error(err))
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"message": "can't moderate reports",
+	})
+}
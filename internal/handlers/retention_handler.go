@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"geowarns/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// RetentionHandler - правила очистки таблиц, журнал и ручной запуск очистки
+type RetentionHandler struct {
+	retentionService *service.RetentionService
+	logger           *zap.Logger
+}
+
+func NewRetentionHandler(retentionService *service.RetentionService, logger *zap.Logger) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+		logger:           logger,
+	}
+}
+
+// GetRetention возвращает правила и итоги очистки по таблицам
+func (h *RetentionHandler) GetRetention(c *fiber.Ctx) error {
+	stats, err := h.retentionService.Stats()
+	if err != nil {
+		h.logger.Error("Failed to get retention stats", zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get retention stats",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "retention",
+		"data": fiber.Map{
+			"policies": h.retentionService.Policies(),
+			"tables":   stats,
+		},
+	})
+}
+
+func (h *RetentionHandler) GetRuns(c *fiber.Ctx) error {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid limit parameter",
+			})
+		}
+		limit = n
+	}
+
+	runs, err := h.retentionService.Runs(c.Query("table"), limit)
+	if err != nil {
+		h.logger.Error("Failed to get retention runs", zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get retention runs",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "retention runs",
+		"data":    runs,
+	})
+}
+
+// RunRetention запускает очистку сейчас. По умолчанию - пробный запуск (dry_run),
+// для удаления нужно явно передать {"dry_run": false}.
+func (h *RetentionHandler) RunRetention(c *fiber.Ctx) error {
+	var req struct {
+		DryRun *bool `json:"dry_run"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "can't parse request",
+			})
+		}
+	}
+	dryRun := req.DryRun == nil || *req.DryRun
+
+	runs := h.retentionService.Run(dryRun)
+
+	h.logger.Info("Retention run requested",
+		zap.Bool("dry_run", dryRun),
+		zap.Stringp("actor", actorName(c)))
+	return c.JSON(fiber.Map{
+		"message": "retention run finished",
+		"data":    runs,
+	})
+}
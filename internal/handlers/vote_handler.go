@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"geowarns/internal/models"
+	"geowarns/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type VoteHandler struct {
+	voteService *service.VoteService
+	logger      *zap.Logger
+}
+
+func NewVoteHandler(voteService *service.VoteService, logger *zap.Logger) *VoteHandler {
+	return &VoteHandler{
+		voteService: voteService,
+		logger:      logger,
+	}
+}
+
+func (h *VoteHandler) CastVote(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	var req models.IncidentVoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+
+	summary, err := h.voteService.CastVote(uint(id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidVote):
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "incident not found",
+			})
+		case errors.Is(err, service.ErrUserNotAlerted):
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		case errors.Is(err, service.ErrIncidentInactive):
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		}
+		h.logger.Error("Failed to cast vote", zap.Uint64("incident_id", id), zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't save vote",
+		})
+	}
+
+	if summary.AutoDeactivated {
+		h.logger.Warn("Incident deactivated by crowd disputes",
+			zap.Uint64("incident_id", id),
+			zap.Float64("confidence", summary.Confidence))
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "vote saved",
+		"data":    summary,
+	})
+}
+
+func (h *VoteHandler) GetVotes(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	summary, votes, err := h.voteService.GetSummary(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "incident not found",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get votes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "incident votes",
+		"data": fiber.Map{
+			"summary": summary,
+			"votes":   votes,
+		},
+	})
+}
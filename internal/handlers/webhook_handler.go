@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"geowarns/internal/models"
 	database "geowarns/internal/repository"
+	"geowarns/pkg/cloudevents"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -34,8 +37,8 @@ func (h *WebhookHandler) ProcessWebhook(c *fiber.Ctx) error {
 		})
 	}
 
-	var payload map[string]interface{}
-	if err := c.BodyParser(&payload); err != nil {
+	payload, err := parseInboundPayload(c)
+	if err != nil {
 		h.logger.Error("Failed to parse webhook payload", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid payload",
@@ -44,9 +47,17 @@ func (h *WebhookHandler) ProcessWebhook(c *fiber.Ctx) error {
 
 	h.logger.Info("Webhook payload received", zap.Any("payload", payload))
 
+	incidentID, _ := payload["incident_id"].(float64)
+	userID, _ := payload["user_id"].(string)
+	if incidentID <= 0 || userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "incident_id and user_id are required",
+		})
+	}
+
 	task := models.WebhookTask{
-		IncidentID:  uint(payload["incident_id"].(float64)),
-		UserID:      payload["user_id"].(string),
+		IncidentID:  uint(incidentID),
+		UserID:      userID,
 		Status:      "pending",
 		Payload:     models.JSON(payload),
 		Attempts:    0,
@@ -77,6 +88,41 @@ func (h *WebhookHandler) ProcessWebhook(c *fiber.Ctx) error {
 	})
 }
 
+// parseInboundPayload читает тело входящего вебхука: обычный JSON или CloudEvent
+// (структурированный или бинарный). Для CloudEvent полезная нагрузка - data события,
+// а его атрибуты сохраняются в поле cloudevent.
+func parseInboundPayload(c *fiber.Ctx) (map[string]interface{}, error) {
+	event, err := cloudevents.Parse(c.Get(fiber.HeaderContentType), func(name string) string {
+		return c.Get(name)
+	}, c.Body())
+	if errors.Is(err, cloudevents.ErrNotCloudEvent) {
+		var payload map[string]interface{}
+		if err := c.BodyParser(&payload); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(event.Data, &payload); err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return nil, errors.New("cloudevent has no data")
+	}
+	payload["cloudevent"] = map[string]interface{}{
+		"id":      event.ID,
+		"source":  event.Source,
+		"type":    event.Type,
+		"subject": event.Subject,
+		"time":    event.Time,
+	}
+	return payload, nil
+}
+
 // HealthCheck для проверки состояния сервиса
 func (h *WebhookHandler) HealthCheck(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"geowarns/internal/models"
+	database "geowarns/internal/repository"
+	"geowarns/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// WebhookQueueHandler - администрирование очереди вебхуков
+type WebhookQueueHandler struct {
+	queueService *service.WebhookQueueService
+	logger       *zap.Logger
+}
+
+func NewWebhookQueueHandler(queueService *service.WebhookQueueService, logger *zap.Logger) *WebhookQueueHandler {
+	return &WebhookQueueHandler{
+		queueService: queueService,
+		logger:       logger,
+	}
+}
+
+func (h *WebhookQueueHandler) GetStats(c *fiber.Ctx) error {
+	stats, err := h.queueService.Stats()
+	if err != nil {
+		return h.handleError(c, err, "can't get webhook queue stats")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook queue stats",
+		"data":    stats,
+	})
+}
+
+// GetTasks - задачи по фильтру: status, incident_id, subscriber_id, user_id, from, to
+func (h *WebhookQueueHandler) GetTasks(c *fiber.Ctx) error {
+	filter, err := parseWebhookTaskFilter(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	filter.Status = c.Query("status")
+	if userID := c.Query("user_id"); userID != "" {
+		filter.UserID = &userID
+	}
+	return h.listTasks(c, filter)
+}
+
+func (h *WebhookQueueHandler) GetIncidentTasks(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	incidentID := uint(id)
+	filter := models.WebhookTaskFilter{IncidentID: &incidentID, Status: c.Query("status")}
+	return h.listTasks(c, filter)
+}
+
+func (h *WebhookQueueHandler) GetUserTasks(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+	if strings.TrimSpace(userID) == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid user ID",
+		})
+	}
+
+	filter := models.WebhookTaskFilter{UserID: &userID, Status: c.Query("status")}
+	return h.listTasks(c, filter)
+}
+
+func (h *WebhookQueueHandler) listTasks(c *fiber.Ctx, filter models.WebhookTaskFilter) error {
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	tasks, pageInfo, err := h.queueService.List(filter, page)
+	if err != nil {
+		if isPageRequestError(err) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid pagination parameters",
+				"error":   err.Error(),
+			})
+		}
+		return h.handleError(c, err, "can't get webhook tasks")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "webhook tasks",
+		"data":       tasks,
+		"pagination": pageInfo,
+	})
+}
+
+func (h *WebhookQueueHandler) CancelTask(c *fiber.Ctx) error {
+	return h.applyToTask(c, models.TaskActionCancel, "webhook task cancelled")
+}
+
+func (h *WebhookQueueHandler) RetryTask(c *fiber.Ctx) error {
+	return h.applyToTask(c, models.TaskActionRetry, "webhook task queued for retry")
+}
+
+func (h *WebhookQueueHandler) DeleteTask(c *fiber.Ctx) error {
+	return h.applyToTask(c, models.TaskActionDelete, "webhook task deleted")
+}
+
+func (h *WebhookQueueHandler) applyToTask(c *fiber.Ctx, action, message string) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	var req models.DeadLetterActionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": "can't parse request",
+			})
+		}
+	}
+
+	actor := taskActor(c, req.Note)
+	if err := h.queueService.Apply(uint(id), action, actor); err != nil {
+		return h.handleError(c, err, "can't "+action+" webhook task")
+	}
+
+	h.logger.Info("Webhook task action applied",
+		zap.Uint64("task_id", id),
+		zap.String("action", action),
+		zap.Stringp("actor", actor.Name))
+	return c.JSON(fiber.Map{
+		"message": message,
+	})
+}
+
+// BulkAction отменяет, повторяет или удаляет все задачи, подходящие под фильтр
+func (h *WebhookQueueHandler) BulkAction(c *fiber.Ctx) error {
+	var req models.WebhookTaskBulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+	req.Actor = taskActor(c, req.Note)
+
+	affected, err := h.queueService.Bulk(&req)
+	if err != nil {
+		return h.handleError(c, err, "can't apply bulk action")
+	}
+
+	h.logger.Info("Webhook tasks bulk action applied",
+		zap.String("action", req.Action),
+		zap.Int64("count", affected),
+		zap.Stringp("actor", req.Actor.Name))
+	return c.JSON(fiber.Map{
+		"message": "bulk action applied",
+		"data": fiber.Map{
+			"action":   req.Action,
+			"affected": affected,
+		},
+	})
+}
+
+func (h *WebhookQueueHandler) handleError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidTaskAction),
+		errors.Is(err, service.ErrEmptyTaskFilter):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "webhook task not found",
+		})
+	case errors.Is(err, database.ErrTaskStatusConflict):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	}
+	h.logger.Error("Webhook queue request failed", zap.Error(err))
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"message": message,
+		"error":   err.Error(),
+	})
+}
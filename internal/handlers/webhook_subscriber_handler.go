@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"geowarns/internal/models"
+	"geowarns/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type WebhookSubscriberHandler struct {
+	subscriberService *service.WebhookSubscriberService
+	webhookService    *service.WebhookService
+	logger            *zap.Logger
+}
+
+func NewWebhookSubscriberHandler(
+	subscriberService *service.WebhookSubscriberService,
+	webhookService *service.WebhookService,
+	logger *zap.Logger,
+) *WebhookSubscriberHandler {
+	return &WebhookSubscriberHandler{
+		subscriberService: subscriberService,
+		webhookService:    webhookService,
+		logger:            logger,
+	}
+}
+
+func (h *WebhookSubscriberHandler) GetSubscribers(c *fiber.Ctx) error {
+	subscribers, err := h.subscriberService.List()
+	if err != nil {
+		h.logger.Error("Failed to get webhook subscribers", zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't get webhook subscribers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook subscribers",
+		"data":    subscribers,
+	})
+}
+
+func (h *WebhookSubscriberHandler) CreateSubscriber(c *fiber.Ctx) error {
+	var req models.WebhookSubscriberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+
+	subscriber, secret, err := h.subscriberService.Create(&req)
+	if err != nil {
+		return h.handleError(c, err, "can't create webhook subscriber")
+	}
+
+	response := fiber.Map{
+		"message": "webhook subscriber created",
+		"data":    subscriber,
+	}
+	// Сгенерированный секрет показывается только один раз
+	if secret != "" {
+		response["secret"] = secret
+	}
+	return c.Status(http.StatusCreated).JSON(response)
+}
+
+func (h *WebhookSubscriberHandler) GetSubscriber(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	subscriber, err := h.subscriberService.Get(uint(id))
+	if err != nil {
+		return h.handleError(c, err, "can't get webhook subscriber")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook subscriber",
+		"data":    subscriber,
+	})
+}
+
+// GetCircuit показывает состояние автоматического выключателя получателя
+func (h *WebhookSubscriberHandler) GetCircuit(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	circuit, err := h.subscriberService.GetCircuit(uint(id))
+	if err != nil {
+		return h.handleError(c, err, "can't get webhook circuit")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook circuit",
+		"data":    circuit,
+	})
+}
+
+func (h *WebhookSubscriberHandler) UpdateSubscriber(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	var req models.WebhookSubscriberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+
+	subscriber, err := h.subscriberService.Update(uint(id), &req)
+	if err != nil {
+		return h.handleError(c, err, "can't update webhook subscriber")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook subscriber updated",
+		"data":    subscriber,
+	})
+}
+
+func (h *WebhookSubscriberHandler) DeleteSubscriber(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid ID",
+		})
+	}
+
+	if err := h.subscriberService.Delete(uint(id)); err != nil {
+		return h.handleError(c, err, "can't delete webhook subscriber")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook subscriber deleted",
+	})
+}
+
+// TestMatch показывает, каким получателям ушел бы вебхук по инциденту
+func (h *WebhookSubscriberHandler) TestMatch(c *fiber.Ctx) error {
+	var req models.SubscriberMatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+
+	incident, matches, err := h.subscriberService.TestMatch(&req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "incident not found",
+			})
+		}
+		return h.handleError(c, err, "can't match webhook subscribers")
+	}
+
+	matched := 0
+	for _, m := range matches {
+		if m.Matched {
+			matched++
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook subscribers match",
+		"data": fiber.Map{
+			"incident": incident,
+			"matched":  matched,
+			"matches":  matches,
+		},
+	})
+}
+
+// PreviewPayload показывает тело вебхука до и после шаблона получателя
+func (h *WebhookSubscriberHandler) PreviewPayload(c *fiber.Ctx) error {
+	var req models.PayloadPreviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": "can't parse request",
+		})
+	}
+
+	preview, err := h.webhookService.PreviewPayload(&req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidPayloadPreview):
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"message": err.Error(),
+			})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"message": "webhook subscriber or task not found",
+			})
+		}
+		h.logger.Error("Failed to preview webhook payload", zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"message": "can't preview webhook payload",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "webhook payload preview",
+		"data":    preview,
+	})
+}
+
+func (h *WebhookSubscriberHandler) handleError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidSubscriber),
+		errors.Is(err, service.ErrInvalidCategory),
+		errors.Is(err, service.ErrInvalidSeverity):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"message": err.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "webhook subscriber not found",
+		})
+	}
+	h.logger.Error("Webhook subscriber request failed", zap.Error(err))
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"message": message,
+		"error":   err.Error(),
+	})
+}
@@ -0,0 +1,107 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Default = "ru"
+
+// Supported - языки, на которых могут храниться тексты инцидентов
+var Supported = []string{"ru", "en", "kk"}
+
+// fallbacks - к каким языкам обращаться, если перевода на нужный язык нет.
+// Казахстанские пользователи, как правило, читают по-русски.
+var fallbacks = map[string][]string{
+	"kk": {"ru"},
+	"en": {},
+	"ru": {},
+}
+
+func IsSupported(lang string) bool {
+	for _, l := range Supported {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize приводит тег вида "kk-KZ" или "EN_us" к базовому языку ("kk", "en")
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// ParseAcceptLanguage разбирает заголовок Accept-Language и возвращает
+// поддерживаемые языки в порядке убывания веса q
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+
+	var items []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := Normalize(fields[0])
+		if lang == "" || !IsSupported(lang) {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		items = append(items, weighted{lang: lang, q: q})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+
+	var langs []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if !seen[item.lang] {
+			seen[item.lang] = true
+			langs = append(langs, item.lang)
+		}
+	}
+	return langs
+}
+
+// Resolve выбирает язык из available по списку предпочтений с учетом fallback-цепочек.
+// Если ничего не подошло, возвращается пустая строка - использовать исходный текст.
+func Resolve(preferred []string, available []string) string {
+	has := make(map[string]bool, len(available))
+	for _, l := range available {
+		has[l] = true
+	}
+
+	for _, lang := range preferred {
+		if has[lang] {
+			return lang
+		}
+	}
+	for _, lang := range preferred {
+		for _, fb := range fallbacks[lang] {
+			if has[fb] {
+				return fb
+			}
+		}
+	}
+	return ""
+}
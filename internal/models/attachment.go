@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AttachmentBaseURL - внешний адрес API, с которого отдаются вложения.
+// Пустое значение дает относительные ссылки.
+var AttachmentBaseURL = ""
+
+type IncidentAttachment struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	IncidentID   uint      `gorm:"not null" json:"incident_id"`
+	FileName     string    `gorm:"not null" json:"file_name"`
+	ContentType  string    `gorm:"not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	StorageKey   string    `gorm:"not null" json:"-"`
+	ThumbnailKey *string   `json:"-"`
+	CreatedAt    time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	URL          string  `gorm:"-" json:"url"`
+	ThumbnailURL *string `gorm:"-" json:"thumbnail_url,omitempty"`
+}
+
+func (a *IncidentAttachment) AfterFind(tx *gorm.DB) error {
+	a.fillURLs()
+	return nil
+}
+
+func (a *IncidentAttachment) AfterCreate(tx *gorm.DB) error {
+	a.fillURLs()
+	return nil
+}
+
+func (a *IncidentAttachment) fillURLs() {
+	base := fmt.Sprintf("%s/api/v1/incidents/%d/attachments/%d", AttachmentBaseURL, a.IncidentID, a.ID)
+	a.URL = base + "/content"
+	if a.ThumbnailKey != nil {
+		thumb := base + "/thumbnail"
+		a.ThumbnailURL = &thumb
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+const DefaultIncidentCategory = "other"
+
+// IncidentCategories - допустимые категории инцидентов и пользовательских сообщений
+var IncidentCategories = []string{
+	"fire",
+	"flood",
+	"gas_leak",
+	"road_accident",
+	"road_closure",
+	"infrastructure",
+	"weather",
+	"crime",
+	"evacuation",
+	DefaultIncidentCategory,
+}
+
+func IsValidCategory(category string) bool {
+	for _, c := range IncidentCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
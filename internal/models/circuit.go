@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// WebhookCircuit - состояние автоматического выключателя получателя. В состоянии
+// open доставка получателю приостановлена до RetryAt, в half_open до RetryAt идет
+// одна пробная отправка. FailingSince - начало текущей серии неудачных отправок.
+type WebhookCircuit struct {
+	SubscriberID        uint       `gorm:"primaryKey" json:"subscriber_id"`
+	State               string     `gorm:"not null;default:'closed'" json:"state"`
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+	Trips               int        `gorm:"not null;default:0" json:"trips"`
+	FailingSince        *time.Time `json:"failing_since"`
+	OpenedAt            *time.Time `json:"opened_at"`
+	RetryAt             *time.Time `json:"retry_at"`
+	LastError           *string    `json:"last_error"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func (WebhookCircuit) TableName() string {
+	return "webhook_endpoint_circuits"
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// WebhookTaskFilter отбирает задачи для просмотра и массовых действий над
+// очередью вебхуков. From/To относятся ко времени последнего изменения задачи.
+// Statuses задает статусы, к которым применимо действие, и дополняет Status.
+type WebhookTaskFilter struct {
+	ID           *uint      `json:"-"`
+	IDs          []uint     `json:"ids"`
+	Status       string     `json:"-"`
+	Statuses     []string   `json:"-"`
+	IncidentID   *uint      `json:"incident_id"`
+	SubscriberID *uint      `json:"subscriber_id"`
+	UserID       *string    `json:"user_id"`
+	From         *time.Time `json:"from"`
+	To           *time.Time `json:"to"`
+}
+
+// IsEmpty - фильтр не ограничивает задачи
+func (f WebhookTaskFilter) IsEmpty() bool {
+	return f.ID == nil && len(f.IDs) == 0 && f.Status == "" && f.IncidentID == nil &&
+		f.SubscriberID == nil && f.UserID == nil && f.From == nil && f.To == nil
+}
+
+// WebhookTaskReplay - запись о повторной отправке задачи из очереди недоставленных
+type WebhookTaskReplay struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID           uint      `gorm:"not null" json:"task_id"`
+	ReplayedBy       *string   `json:"replayed_by"`
+	Note             *string   `json:"note"`
+	PreviousAttempts int       `gorm:"not null" json:"previous_attempts"`
+	PreviousError    *string   `json:"previous_error"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// DeadLetterActionRequest - повтор или удаление задач: одной (по ID в пути) или всех подходящих под фильтр.
+// Note - необязательный комментарий к действию.
+type DeadLetterActionRequest struct {
+	WebhookTaskFilter
+	Note *string `json:"note"`
+}
+
+// TaskActor - кто выполняет действие над задачами: Name - владелец токена, Note - его комментарий
+type TaskActor struct {
+	Name *string
+	Note *string
+}
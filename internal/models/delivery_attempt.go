@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// WebhookDeliveryAttempt - запись об одной попытке доставки вебхука
+type WebhookDeliveryAttempt struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID         uint      `gorm:"not null" json:"task_id"`
+	SubscriberID   *uint     `json:"subscriber_id"`
+	Attempt        int       `gorm:"not null" json:"attempt"`
+	URL            string    `gorm:"not null" json:"url"`
+	RequestHeaders JSON      `gorm:"type:jsonb" json:"request_headers"`
+	PayloadSHA256  string    `gorm:"column:payload_sha256" json:"payload_sha256"`
+	ResponseStatus *int      `json:"response_status"`
+	ResponseBody   *string   `json:"response_body"`
+	LatencyMs      int64     `gorm:"not null;default:0" json:"latency_ms"`
+	Error          *string   `json:"error"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type DeliveryAttemptFilter struct {
+	From *time.Time
+	To   *time.Time
+}
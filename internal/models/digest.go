@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// WebhookDigestEntry - событие, накопленное для сводки получателя в режиме digest.
+// DigestTaskID заполняется, когда событие попадает в отправленную сводку.
+type WebhookDigestEntry struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriberID uint      `gorm:"not null" json:"subscriber_id"`
+	Event        string    `gorm:"not null" json:"event"`
+	IncidentID   uint      `gorm:"not null" json:"incident_id"`
+	UserID       string    `json:"user_id"`
+	Language     string    `json:"language"`
+	Payload      JSON      `gorm:"type:jsonb" json:"payload"`
+	DigestTaskID *uint     `json:"digest_task_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AlertBatch - результат разбора события по получателям: задачи на немедленную
+// отправку и записи для сводок
+type AlertBatch struct {
+	Tasks         []WebhookTask
+	DigestEntries []WebhookDigestEntry
+}
+
+func (b *AlertBatch) IsEmpty() bool {
+	return len(b.Tasks) == 0 && len(b.DigestEntries) == 0
+}
+
+// Add добавляет задачу: для получателя в режиме digest она превращается в запись сводки
+func (b *AlertBatch) Add(task WebhookTask, subscriber *WebhookSubscriber) {
+	subscriberID := subscriber.ID
+	if subscriber.DeliveryMode == DeliveryModeDigest {
+		b.DigestEntries = append(b.DigestEntries, WebhookDigestEntry{
+			SubscriberID: subscriberID,
+			Event:        task.Event,
+			IncidentID:   task.IncidentID,
+			UserID:       task.UserID,
+			Language:     task.Language,
+			Payload:      task.Payload,
+			CreatedAt:    time.Now(),
+		})
+		return
+	}
+	task.SubscriberID = &subscriberID
+	b.Tasks = append(b.Tasks, task)
+}
@@ -0,0 +1,17 @@
+package models
+
+// IncidentSearchRequest - полнотекстовый поиск по заголовку и описанию
+type IncidentSearchRequest struct {
+	Query     string
+	Filter    IncidentFilter
+	Limit     int
+	Offset    int
+	WithTotal bool
+}
+
+type IncidentSearchResult struct {
+	Incident             Incident `json:"incident"`
+	Rank                 float64  `json:"rank"`
+	TitleHighlight       string   `json:"title_highlight"`
+	DescriptionHighlight *string  `json:"description_highlight"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// IncidentUpdate - запись в хронологии инцидента ("открыта одна полоса" и т.п.)
+type IncidentUpdate struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	IncidentID    uint      `gorm:"not null" json:"incident_id"`
+	Message       string    `gorm:"not null" json:"message"`
+	Author        *string   `json:"author"`
+	Notify        bool      `gorm:"not null;default:false" json:"notify"`
+	NotifiedUsers int       `gorm:"not null;default:0" json:"notified_users"`
+	CreatedAt     time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+type IncidentUpdateCreateRequest struct {
+	Message string  `json:"message" validate:"required"`
+	Author  *string `json:"author"`
+	Notify  bool    `json:"notify"`
+}
@@ -14,4 +14,5 @@ type LocationCheckRequest struct {
 	UserID    string  `json:"user_id" validate:"required"`
 	Latitude  float64 `json:"latitude" validate:"required,min=-90,max=90"`
 	Longitude float64 `json:"longitude" validate:"required,min=-180,max=180"`
+	Language  string  `json:"language"`
 }
@@ -3,17 +3,40 @@ package models
 import "time"
 
 type Incident struct {
-	ID            uint           `gorm:"primary_key" json:"id"`
-	Title         string         `gorm:"not null" json:"title"`
-	Description   *string         `json:"description"`
-	Latitude      float64        `gorm:"not null" json:"latitude"`
-	Longitude     float64        `gorm:"not null" json:"longitude"`
-	Radius        float64        `gorm:"not null" json:"radius"`
-	IsActive      bool           `gorm:"not null;default:true" json:"is_active"`
-	CreatedAt     time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt     time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
-	WebhookTasks  []WebhookTask  `gorm:"foreignKey:IncidentID" json:"-"`
-	IncidentStats []IncidentStat `gorm:"foreignKey:IncidentID" json:"-"`
+	ID            uint                  `gorm:"primary_key" json:"id"`
+	Title         string                `gorm:"not null" json:"title"`
+	Description   *string               `json:"description"`
+	Latitude      float64               `gorm:"not null" json:"latitude"`
+	Longitude     float64               `gorm:"not null" json:"longitude"`
+	Radius        float64               `gorm:"not null" json:"radius"`
+	Category      string                `gorm:"not null;default:'other'" json:"category"`
+	Severity      string                `gorm:"not null;default:'medium'" json:"severity"`
+	IsActive      bool                  `gorm:"not null;default:true" json:"is_active"`
+	Language      string                `gorm:"not null;default:'ru'" json:"language"`
+	Confidence    *float64              `json:"confidence"`
+	NeedsReview   bool                  `gorm:"not null;default:false" json:"needs_review"`
+	CreatedAt     time.Time             `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time             `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	WebhookTasks  []WebhookTask         `gorm:"foreignKey:IncidentID" json:"-"`
+	IncidentStats []IncidentStat        `gorm:"foreignKey:IncidentID" json:"-"`
+	Translations  []IncidentTranslation `gorm:"foreignKey:IncidentID" json:"translations,omitempty"`
+	Attachments   []IncidentAttachment  `gorm:"foreignKey:IncidentID" json:"attachments,omitempty"`
+}
+
+// IncidentTranslation - заголовок и описание инцидента на другом языке
+type IncidentTranslation struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	IncidentID  uint      `gorm:"not null" json:"-"`
+	Language    string    `gorm:"not null" json:"language"`
+	Title       string    `gorm:"not null" json:"title"`
+	Description *string   `json:"description"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type IncidentTranslationInput struct {
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
 }
 
 type IncidentStat struct {
@@ -31,6 +54,12 @@ type IncidentCreateRequest struct {
 	Longitude   float64 `json:"longitude" validate:"required,min=-180,max=180"`
 	Radius      float64 `json:"radius" validate:"required,min=1"`
 	IsActive    *bool   `json:"is_active"`
+	Category    string  `json:"category"`
+	Severity    string  `json:"severity"`
+	Language    string  `json:"language"`
+	NeedsReview *bool   `json:"needs_review"`
+
+	Translations map[string]IncidentTranslationInput `json:"translations"`
 }
 
 type IncidentStats struct {
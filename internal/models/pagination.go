@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// PageRequest описывает запрос одной страницы списка (курсорная пагинация)
+type PageRequest struct {
+	Cursor    string
+	Limit     int
+	SortBy    string
+	SortOrder string
+	WithTotal bool
+}
+
+// PageInfo возвращается клиенту вместе со страницей
+type PageInfo struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	Limit      int    `json:"limit"`
+	SortBy     string `json:"sort_by"`
+	SortOrder  string `json:"sort_order"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// BBox - прямоугольная область в градусах
+type BBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+func (b BBox) Contains(lat, lng float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+type IncidentFilter struct {
+	IsActive    *bool
+	NeedsReview *bool
+	Category    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	BBox        *BBox
+}
+
+type LocationCheckFilter struct {
+	UserID string
+	From   *time.Time
+	To     *time.Time
+	BBox   *BBox
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReportStatusPending  = "pending"
+	ReportStatusApproved = "approved"
+	ReportStatusMerged   = "merged"
+	ReportStatusRejected = "rejected"
+)
+
+// IncidentReport - сообщение пользователя об опасности, ожидающее модерации
+type IncidentReport struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClusterID   uint       `gorm:"not null" json:"cluster_id"`
+	UserID      string     `gorm:"not null" json:"user_id"`
+	Latitude    float64    `gorm:"not null" json:"latitude"`
+	Longitude   float64    `gorm:"not null" json:"longitude"`
+	Category    string     `gorm:"not null" json:"category"`
+	Text        string     `gorm:"not null" json:"text"`
+	PhotoKey    *string    `json:"-"`
+	PhotoType   *string    `json:"-"`
+	HasPhoto    bool       `gorm:"-" json:"has_photo"`
+	Status      string     `gorm:"not null;default:'pending'" json:"status"`
+	IncidentID  *uint      `json:"incident_id"`
+	CreatedAt   time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	ModeratedAt *time.Time `json:"moderated_at"`
+	// AccessTokenHash - SHA-256 токена, по которому автор читает свое сообщение.
+	// Сам токен (AccessToken) возвращается только в ответе на отправку.
+	AccessTokenHash *string `json:"-"`
+	AccessToken     string  `gorm:"-" json:"access_token,omitempty"`
+}
+
+// ReportCluster объединяет сообщения одной категории, поступившие рядом друг с другом
+// в пределах временного окна. Модератор работает с кластером целиком.
+type ReportCluster struct {
+	ID            uint             `gorm:"primaryKey;autoIncrement" json:"id"`
+	Category      string           `gorm:"not null" json:"category"`
+	Latitude      float64          `gorm:"not null" json:"latitude"`
+	Longitude     float64          `gorm:"not null" json:"longitude"`
+	ReportCount   int              `gorm:"not null;default:0" json:"report_count"`
+	Status        string           `gorm:"not null;default:'pending'" json:"status"`
+	IncidentID    *uint            `json:"incident_id"`
+	Moderator     *string          `json:"moderator"`
+	Reason        *string          `json:"reason"`
+	FirstReportAt time.Time        `gorm:"not null" json:"first_report_at"`
+	LastReportAt  time.Time        `gorm:"not null" json:"last_report_at"`
+	ModeratedAt   *time.Time       `json:"moderated_at"`
+	Reports       []IncidentReport `gorm:"foreignKey:ClusterID" json:"reports,omitempty"`
+}
+
+type IncidentReportRequest struct {
+	UserID    string  `json:"user_id" form:"user_id"`
+	Latitude  float64 `json:"latitude" form:"latitude"`
+	Longitude float64 `json:"longitude" form:"longitude"`
+	Category  string  `json:"category" form:"category"`
+	Text      string  `json:"text" form:"text"`
+}
+
+// Moderator в запросах модерации заполняется владельцем токена, а не клиентом
+type ReportApproveRequest struct {
+	Moderator   *string `json:"-"`
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
+	Radius      float64 `json:"radius"`
+	Severity    string  `json:"severity"`
+	Language    string  `json:"language"`
+}
+
+type ReportMergeRequest struct {
+	Moderator  *string `json:"-"`
+	IncidentID uint    `json:"incident_id"`
+}
+
+type ReportRejectRequest struct {
+	Moderator *string `json:"-"`
+	Reason    *string `json:"reason"`
+}
+
+func (r *IncidentReport) AfterFind(tx *gorm.DB) error {
+	r.HasPhoto = r.PhotoKey != nil
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RetentionPolicy - правило очистки таблицы: удаляются строки старше MaxAge и строки
+// сверх MaxRows самых новых. Statuses ограничивает правило строками с этими статусами
+// (для таблиц со статусом). Нулевые MaxAge и MaxRows не ограничивают.
+type RetentionPolicy struct {
+	Table    string        `json:"table"`
+	MaxAge   time.Duration `json:"-"`
+	Statuses []string      `json:"statuses,omitempty"`
+	MaxRows  int64         `json:"max_rows,omitempty"`
+}
+
+func (p RetentionPolicy) MarshalJSON() ([]byte, error) {
+	type policy RetentionPolicy
+	var maxAge string
+	if p.MaxAge > 0 {
+		maxAge = p.MaxAge.String()
+	}
+	return json.Marshal(struct {
+		policy
+		MaxAge string `json:"max_age,omitempty"`
+	}{policy(p), maxAge})
+}
+
+// RetentionRun - результат применения правила очистки. При DryRun строки не
+// удаляются, а Rows - сколько строк было бы удалено.
+type RetentionRun struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Table      string    `gorm:"column:table_name;not null" json:"table"`
+	DryRun     bool      `gorm:"not null" json:"dry_run"`
+	Rows       int64     `gorm:"column:affected_rows;not null" json:"rows"`
+	Batches    int       `gorm:"not null" json:"batches"`
+	StartedAt  time.Time `gorm:"not null" json:"started_at"`
+	DurationMs int64     `gorm:"not null" json:"duration_ms"`
+	Error      *string   `json:"error"`
+}
+
+func (RetentionRun) TableName() string {
+	return "retention_runs"
+}
+
+// RetentionTableStats - итоги очистки таблицы по журналу запусков
+type RetentionTableStats struct {
+	Table       string     `json:"table"`
+	Runs        int64      `json:"runs"`
+	DeletedRows int64      `json:"deleted_rows"`
+	LastRunAt   *time.Time `json:"last_run_at"`
+	LastDeleted int64      `json:"last_deleted"`
+	LastError   *string    `json:"last_error"`
+}
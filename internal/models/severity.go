@@ -0,0 +1,32 @@
+package models
+
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+
+	DefaultIncidentSeverity = SeverityMedium
+)
+
+// IncidentSeverities - уровни опасности инцидента по возрастанию
+var IncidentSeverities = []string{
+	SeverityLow,
+	SeverityMedium,
+	SeverityHigh,
+	SeverityCritical,
+}
+
+// SeverityRank возвращает порядковый номер уровня опасности или -1 для неизвестного уровня
+func SeverityRank(severity string) int {
+	for i, s := range IncidentSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+func IsValidSeverity(severity string) bool {
+	return SeverityRank(severity) >= 0
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	VoteConfirm = "confirm"
+	VoteDispute = "dispute"
+)
+
+// IncidentVote - подтверждение ("еще здесь") или опровержение ("уже нет") инцидента
+// пользователем. У пользователя один голос на инцидент, повторный голос его заменяет.
+type IncidentVote struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	IncidentID     uint      `gorm:"not null" json:"incident_id"`
+	UserID         string    `gorm:"not null" json:"user_id"`
+	Vote           string    `gorm:"not null" json:"vote"`
+	Latitude       float64   `gorm:"not null" json:"latitude"`
+	Longitude      float64   `gorm:"not null" json:"longitude"`
+	DistanceMeters float64   `gorm:"not null" json:"distance_meters"`
+	Weight         float64   `gorm:"not null" json:"weight"`
+	CreatedAt      time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+type IncidentVoteRequest struct {
+	UserID    string  `json:"user_id"`
+	Vote      string  `json:"vote"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type IncidentVoteSummary struct {
+	IncidentID      uint    `json:"incident_id"`
+	Confirmations   int     `json:"confirmations"`
+	Disputes        int     `json:"disputes"`
+	ConfirmWeight   float64 `json:"confirm_weight"`
+	DisputeWeight   float64 `json:"dispute_weight"`
+	Confidence      float64 `json:"confidence"`
+	NeedsReview     bool    `json:"needs_review"`
+	IsActive        bool    `json:"is_active"`
+	AutoDeactivated bool    `json:"auto_deactivated"`
+}
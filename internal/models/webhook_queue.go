@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+const (
+	TaskActionCancel = "cancel"
+	TaskActionRetry  = "retry"
+	TaskActionDelete = "delete"
+)
+
+// TaskAgeBuckets - границы возрастных групп задач в статистике очереди
+var TaskAgeBuckets = []struct {
+	Name string
+	Max  time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+// TaskAgeOlder - группа задач старше последней границы TaskAgeBuckets
+const TaskAgeOlder = "older"
+
+// WebhookQueueStats - состояние очереди вебхуков. Age - число задач по статусам
+// и возрасту (по времени создания): "1m" - моложе минуты, "5m" - от минуты до
+// пяти и так далее.
+type WebhookQueueStats struct {
+	Counts        map[string]int64            `json:"counts"`
+	Age           map[string]map[string]int64 `json:"age"`
+	OldestPending *OldestPendingTask          `json:"oldest_pending"`
+}
+
+// OldestPendingTask - задача, дольше всех ждущая отправки после наступления next_attempt.
+// LatencySeconds - задержка очереди: сколько задача ждет обработчика; AgeSeconds - сколько
+// прошло с создания задачи, включая паузы между повторами.
+type OldestPendingTask struct {
+	TaskID         uint      `json:"task_id"`
+	SubscriberID   *uint     `json:"subscriber_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	NextAttempt    time.Time `json:"next_attempt"`
+	LatencySeconds float64   `json:"latency_seconds"`
+	AgeSeconds     float64   `json:"age_seconds"`
+}
+
+// WebhookTaskBulkRequest - действие над всеми задачами, подходящими под фильтр
+type WebhookTaskBulkRequest struct {
+	WebhookTaskFilter
+	Action string    `json:"action"`
+	Status string    `json:"status"`
+	Note   *string   `json:"note"`
+	Actor  TaskActor `json:"-"`
+}
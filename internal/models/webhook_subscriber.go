@@ -0,0 +1,285 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"geowarns/internal/geo"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DeliveryModeSingle - отдельный вебхук на каждый инцидент
+	DeliveryModeSingle = "single"
+	// DeliveryModeCombined - один вебхук на проверку локации со всеми найденными инцидентами
+	DeliveryModeCombined = "combined"
+	// DeliveryModeDigest - события накапливаются и отправляются сводкой по расписанию
+	DeliveryModeDigest = "digest"
+
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+
+	// Конверт тела вебхука: без конверта или CloudEvents 1.0 в структурированном
+	// (событие целиком в теле) или бинарном (атрибуты в заголовках ce-*) режиме
+	EnvelopeNone                  = "none"
+	EnvelopeCloudEventsStructured = "cloudevents-structured"
+	EnvelopeCloudEventsBinary     = "cloudevents-binary"
+)
+
+var Envelopes = []string{
+	EnvelopeNone,
+	EnvelopeCloudEventsStructured,
+	EnvelopeCloudEventsBinary,
+}
+
+var DeliveryModes = []string{
+	DeliveryModeSingle,
+	DeliveryModeCombined,
+	DeliveryModeDigest,
+}
+
+var DigestIntervals = map[string]time.Duration{
+	DigestHourly: time.Hour,
+	DigestDaily:  24 * time.Hour,
+}
+
+func IsValidDeliveryMode(mode string) bool {
+	for _, m := range DeliveryModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidEnvelope(envelope string) bool {
+	for _, e := range Envelopes {
+		if e == envelope {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvents - события, на которые можно подписаться
+var WebhookEvents = []string{
+	EventUserNearIncident,
+	EventIncidentUpdate,
+	EventIncidentCreated,
+	EventIncidentUpdated,
+	EventIncidentResolved,
+	EventIncidentDeleted,
+}
+
+func IsValidWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscriber - внешняя система, получающая вебхуки. Пустой список
+// EventTypes означает подписку на все события. Region, MinSeverity и Categories
+// ограничивают инциденты, о которых получатель узнает; пустые значения не ограничивают.
+// PayloadTemplate переводит стандартное тело вебхука в формат получателя (см. webhooktmpl).
+// Contact - контакт ответственного за получателя, передается оператору при автоматическом отключении.
+// MaxRPS и MaxInFlight ограничивают частоту запросов и число одновременных отправок получателю.
+type WebhookSubscriber struct {
+	ID              uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	URL             string     `gorm:"not null" json:"url"`
+	Secret          string     `json:"-"`
+	PreviousSecret  *string    `json:"-"`
+	HasSecret       bool       `gorm:"-" json:"has_secret"`
+	EventTypes      StringList `gorm:"type:jsonb;not null;default:'[]'" json:"event_types"`
+	Region          *GeoRegion `gorm:"type:jsonb" json:"region"`
+	MinSeverity     *string    `json:"min_severity"`
+	Categories      StringList `gorm:"type:jsonb;not null;default:'[]'" json:"categories"`
+	Enabled         bool       `gorm:"not null;default:true" json:"enabled"`
+	DeliveryMode    string     `gorm:"not null;default:'single'" json:"delivery_mode"`
+	DigestInterval  *string    `json:"digest_interval"`
+	LastDigestAt    *time.Time `json:"last_digest_at"`
+	PayloadTemplate *string    `json:"payload_template"`
+	Envelope        string     `gorm:"not null;default:'none'" json:"envelope"`
+	Contact         *string    `json:"contact"`
+	MaxRPS          *float64   `gorm:"column:max_rps" json:"max_rps"`
+	MaxInFlight     *int       `json:"max_in_flight"`
+	Description     *string    `json:"description"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (s *WebhookSubscriber) AfterFind(tx *gorm.DB) error {
+	s.HasSecret = s.Secret != ""
+	return nil
+}
+
+// Accepts проверяет, подписан ли получатель на событие
+func (s *WebhookSubscriber) Accepts(event string) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	return s.EventTypes.Contains(event)
+}
+
+// Match проверяет, должен ли получатель узнать о событии по инциденту.
+// Если нет, возвращается причина.
+func (s *WebhookSubscriber) Match(event string, incident *Incident) (bool, string) {
+	switch {
+	case !s.Enabled:
+		return false, "subscriber is disabled"
+	case !s.Accepts(event):
+		return false, fmt.Sprintf("not subscribed to %s", event)
+	case s.Region != nil && !s.Region.Contains(incident.Latitude, incident.Longitude):
+		return false, "incident is outside the region"
+	case s.MinSeverity != nil && SeverityRank(incident.Severity) < SeverityRank(*s.MinSeverity):
+		return false, fmt.Sprintf("severity %s is below %s", incident.Severity, *s.MinSeverity)
+	case len(s.Categories) > 0 && !s.Categories.Contains(incident.Category):
+		return false, fmt.Sprintf("category %s is not subscribed", incident.Category)
+	}
+	return true, ""
+}
+
+// DigestPeriodEnd возвращает конец последнего завершившегося периода сводки:
+// начало текущего часа или суток (UTC)
+func (s *WebhookSubscriber) DigestPeriodEnd(now time.Time) time.Time {
+	interval := DigestIntervals[DigestHourly]
+	if s.DigestInterval != nil {
+		if d, ok := DigestIntervals[*s.DigestInterval]; ok {
+			interval = d
+		}
+	}
+	return now.UTC().Truncate(interval)
+}
+
+// Secrets возвращает активные секреты подписи: текущий и, на время смены, предыдущий
+func (s *WebhookSubscriber) Secrets() []string {
+	var secrets []string
+	if s.Secret != "" {
+		secrets = append(secrets, s.Secret)
+	}
+	if s.PreviousSecret != nil && *s.PreviousSecret != "" {
+		secrets = append(secrets, *s.PreviousSecret)
+	}
+	return secrets
+}
+
+// WebhookSubscriberRequest - создание и частичное изменение получателя, nil-поля не меняются
+type WebhookSubscriberRequest struct {
+	URL             *string    `json:"url"`
+	Secret          *string    `json:"secret"`
+	PreviousSecret  *string    `json:"previous_secret"`
+	EventTypes      *[]string  `json:"event_types"`
+	Region          *GeoRegion `json:"region"`
+	MinSeverity     *string    `json:"min_severity"`
+	Categories      *[]string  `json:"categories"`
+	Enabled         *bool      `json:"enabled"`
+	DeliveryMode    *string    `json:"delivery_mode"`
+	DigestInterval  *string    `json:"digest_interval"`
+	PayloadTemplate *string    `json:"payload_template"`
+	Envelope        *string    `json:"envelope"`
+	Contact         *string    `json:"contact"`
+	MaxRPS          *float64   `json:"max_rps"`
+	MaxInFlight     *int       `json:"max_in_flight"`
+	Description     *string    `json:"description"`
+}
+
+// SubscriberMatch - результат проверки, дойдет ли событие до получателя
+type SubscriberMatch struct {
+	Subscriber WebhookSubscriber `json:"subscriber"`
+	Matched    bool              `json:"matched"`
+	Reason     string            `json:"reason,omitempty"`
+}
+
+// SubscriberMatchRequest описывает инцидент для проверки подписок: существующий
+// (IncidentID) или гипотетический (координаты, категория и уровень опасности)
+type SubscriberMatchRequest struct {
+	IncidentID *uint   `json:"incident_id"`
+	Event      string  `json:"event"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Category   string  `json:"category"`
+	Severity   string  `json:"severity"`
+}
+
+// PayloadPreviewRequest - отрисовка тела вебхука для проверки шаблона. Шаблон берется
+// из Template или у получателя SubscriberID; событие - из задачи TaskID или образец Event.
+type PayloadPreviewRequest struct {
+	SubscriberID *uint   `json:"subscriber_id"`
+	TaskID       *uint   `json:"task_id"`
+	Event        string  `json:"event"`
+	Template     *string `json:"template"`
+}
+
+// GeoRegion - область подписки: прямоугольник или многоугольник
+type GeoRegion struct {
+	BBox    *BBox       `json:"bbox,omitempty"`
+	Polygon []geo.Point `json:"polygon,omitempty"`
+}
+
+func (r *GeoRegion) IsEmpty() bool {
+	return r.BBox == nil && len(r.Polygon) == 0
+}
+
+func (r *GeoRegion) Contains(lat, lng float64) bool {
+	if r.BBox != nil && !r.BBox.Contains(lat, lng) {
+		return false
+	}
+	if len(r.Polygon) > 0 && !geo.PointInPolygon(lat, lng, r.Polygon) {
+		return false
+	}
+	return true
+}
+
+func (r GeoRegion) Value() (driver.Value, error) {
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+func (r *GeoRegion) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	}
+	return errors.New("type assertion to []byte failed")
+}
+
+// StringList хранит список строк в колонке jsonb
+type StringList []string
+
+func (l StringList) Contains(value string) bool {
+	for _, v := range l {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case nil:
+		*l = nil
+		return nil
+	}
+	return errors.New("type assertion to []byte failed")
+}
@@ -7,16 +7,52 @@ import (
 	"time"
 )
 
+const (
+	EventUserNearIncident = "user_near_incident"
+	EventIncidentUpdate   = "incident.update"
+	// EventUserNearIncidents - объединенное оповещение обо всех инцидентах одной
+	// проверки локации (для получателей в режиме combined)
+	EventUserNearIncidents = "user_near_incidents"
+	// EventDigest - сводка накопленных событий для получателя в режиме digest
+	EventDigest = "digest"
+
+	// События жизненного цикла инцидента, без привязки к пользователю
+	EventIncidentCreated  = "incident.created"
+	EventIncidentUpdated  = "incident.updated"
+	EventIncidentResolved = "incident.resolved"
+	EventIncidentDeleted  = "incident.deleted"
+)
+
+func IsIncidentLifecycleEvent(event string) bool {
+	switch event {
+	case EventIncidentCreated, EventIncidentUpdated, EventIncidentResolved, EventIncidentDeleted:
+		return true
+	}
+	return false
+}
+
 type WebhookTask struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	IncidentID  uint      `json:"incident_id"`
-	UserID      string    `json:"user_id"`
-	Status      string    `gorm:"type:string;default:'pending'" json:"status"`
-	Payload     JSON      `gorm:"type:jsonb" json:"payload"`
-	Attempts    int       `gorm:"default:0" json:"attempts"`
-	NextAttempt time.Time `json:"next_attempt"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	IncidentID   uint       `json:"incident_id"`
+	UserID       string     `json:"user_id"`
+	SubscriberID *uint      `json:"subscriber_id"`
+	Event        string     `gorm:"default:'user_near_incident'" json:"event"`
+	Status       string     `gorm:"type:string;default:'pending'" json:"status"`
+	Payload      JSON       `gorm:"type:jsonb" json:"payload"`
+	Language     string     `json:"language"`
+	Attempts     int        `gorm:"default:0" json:"attempts"`
+	NextAttempt  time.Time  `json:"next_attempt"`
+	LastError    *string    `json:"last_error"`
+	LockedBy     *string    `json:"locked_by"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// AlertRecipient - пользователь, получивший оповещение об инциденте, и его язык
+type AlertRecipient struct {
+	UserID   string
+	Language string
 }
 
 type JSON map[string]interface{}
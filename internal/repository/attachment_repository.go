@@ -0,0 +1,42 @@
+package database
+
+import (
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) Create(attachment *models.IncidentAttachment) error {
+	return r.db.Create(attachment).Error
+}
+
+func (r *AttachmentRepository) GetByIncident(incidentID uint) ([]models.IncidentAttachment, error) {
+	var attachments []models.IncidentAttachment
+	err := r.db.
+		Where("incident_id = ?", incidentID).
+		Order("created_at ASC, id ASC").
+		Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *AttachmentRepository) GetByID(incidentID, id uint) (*models.IncidentAttachment, error) {
+	var attachment models.IncidentAttachment
+	if err := r.db.
+		Where("id = ? AND incident_id = ?", id, incidentID).
+		First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *AttachmentRepository) Delete(id uint) error {
+	return r.db.Delete(&models.IncidentAttachment{}, id).Error
+}
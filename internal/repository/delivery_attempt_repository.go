@@ -0,0 +1,82 @@
+package database
+
+import (
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var deliveryAttemptSortColumns = map[string]sortColumn{
+	"id":         {column: "id", kind: sortKindInt},
+	"created_at": {column: "created_at", kind: sortKindTime},
+}
+
+type DeliveryAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewDeliveryAttemptRepository(db *gorm.DB) *DeliveryAttemptRepository {
+	return &DeliveryAttemptRepository{db: db}
+}
+
+func (r *DeliveryAttemptRepository) Create(attempt *models.WebhookDeliveryAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+func (r *DeliveryAttemptRepository) GetByTask(taskID uint) ([]models.WebhookDeliveryAttempt, error) {
+	var attempts []models.WebhookDeliveryAttempt
+	err := r.db.
+		Where("task_id = ?", taskID).
+		Order("created_at ASC, id ASC").
+		Find(&attempts).Error
+	return attempts, err
+}
+
+// ListBySubscriber возвращает страницу попыток доставки получателю
+func (r *DeliveryAttemptRepository) ListBySubscriber(subscriberID uint, filter models.DeliveryAttemptFilter, page models.PageRequest) ([]models.WebhookDeliveryAttempt, models.PageInfo, error) {
+	col, err := resolveSort(&page, deliveryAttemptSortColumns, "created_at")
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	query := r.db.Model(&models.WebhookDeliveryAttempt{}).Where("subscriber_id = ?", subscriberID)
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if page.WithTotal {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, models.PageInfo{}, err
+		}
+	}
+
+	query, err = applyPage(query.Session(&gorm.Session{}), &page, col)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	var attempts []models.WebhookDeliveryAttempt
+	if err := query.Find(&attempts).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	info := buildPageInfo(&page, col, len(attempts), func(i int) (interface{}, uint) {
+		attempt := attempts[i]
+		if page.SortBy == "created_at" {
+			return attempt.CreatedAt, attempt.ID
+		}
+		return attempt.ID, attempt.ID
+	})
+	if len(attempts) > page.Limit {
+		attempts = attempts[:page.Limit]
+	}
+	if page.WithTotal {
+		info.Total = &total
+	}
+
+	return attempts, info, nil
+}
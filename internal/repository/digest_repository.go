@@ -0,0 +1,137 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DigestRepository struct {
+	db *gorm.DB
+}
+
+func NewDigestRepository(db *gorm.DB) *DigestRepository {
+	return &DigestRepository{db: db}
+}
+
+// FlushDue собирает сводки, период которых завершился к now: для каждого получателя
+// накопленные записи привязываются к одной новой задаче EventDigest. Получатель
+// блокируется на время сборки (SKIP LOCKED), поэтому при нескольких репликах сводка
+// за период создается один раз. Записи получателя, переключенного из режима digest,
+// отправляются сразу.
+func (r *DigestRepository) FlushDue(now time.Time) ([]models.WebhookTask, error) {
+	var ids []uint
+	err := r.db.Model(&models.WebhookSubscriber{}).
+		Where("enabled = ?", true).
+		Where(`delivery_mode = ? OR EXISTS (
+			SELECT 1 FROM webhook_digest_entries e
+			WHERE e.subscriber_id = webhook_subscribers.id AND e.digest_task_id IS NULL)`,
+			models.DeliveryModeDigest).
+		Order("id ASC").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []models.WebhookTask
+	for _, id := range ids {
+		task, err := r.flushSubscriber(id, now)
+		if err != nil {
+			return tasks, err
+		}
+		if task != nil {
+			tasks = append(tasks, *task)
+		}
+	}
+	return tasks, nil
+}
+
+func (r *DigestRepository) flushSubscriber(subscriberID uint, now time.Time) (*models.WebhookTask, error) {
+	var task *models.WebhookTask
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var subscriber models.WebhookSubscriber
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			First(&subscriber, subscriberID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Получателя обрабатывает другая реплика или он удален
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		periodEnd := now.UTC()
+		if subscriber.DeliveryMode == models.DeliveryModeDigest {
+			periodEnd = subscriber.DigestPeriodEnd(now)
+			if subscriber.LastDigestAt != nil && !subscriber.LastDigestAt.Before(periodEnd) {
+				return nil
+			}
+		}
+
+		var entries []models.WebhookDigestEntry
+		err = tx.
+			Where("subscriber_id = ? AND digest_task_id IS NULL AND created_at < ?", subscriberID, periodEnd).
+			Order("id ASC").
+			Find(&entries).Error
+		if err != nil {
+			return err
+		}
+
+		if len(entries) > 0 {
+			periodStart := entries[0].CreatedAt.UTC()
+			if subscriber.LastDigestAt != nil && subscriber.LastDigestAt.Before(periodStart) {
+				periodStart = subscriber.LastDigestAt.UTC()
+			}
+
+			subID := subscriber.ID
+			task = &models.WebhookTask{
+				// Задача привязана к первому инциденту сводки, весь состав - в записях сводки
+				IncidentID:   entries[0].IncidentID,
+				SubscriberID: &subID,
+				Event:        models.EventDigest,
+				Status:       "pending",
+				NextAttempt:  time.Now(),
+				Payload: models.JSON{
+					"period_start": periodStart,
+					"period_end":   periodEnd,
+				},
+			}
+			if subscriber.DigestInterval != nil {
+				task.Payload["interval"] = *subscriber.DigestInterval
+			}
+			if err := tx.Create(task).Error; err != nil {
+				return err
+			}
+
+			entryIDs := make([]uint, 0, len(entries))
+			for _, entry := range entries {
+				entryIDs = append(entryIDs, entry.ID)
+			}
+			err = tx.Model(&models.WebhookDigestEntry{}).
+				Where("id IN ?", entryIDs).
+				Update("digest_task_id", task.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&subscriber).UpdateColumn("last_digest_at", periodEnd).Error
+	})
+
+	return task, err
+}
+
+// GetEntriesByTask возвращает записи, вошедшие в сводку
+func (r *DigestRepository) GetEntriesByTask(taskID uint) ([]models.WebhookDigestEntry, error) {
+	var entries []models.WebhookDigestEntry
+	err := r.db.
+		Where("digest_task_id = ?", taskID).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error
+	return entries, err
+}
@@ -3,8 +3,18 @@ package database
 import (
 	"geowarns/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10"
+
+var incidentSortColumns = map[string]sortColumn{
+	"id":         {column: "id", kind: sortKindInt},
+	"created_at": {column: "created_at", kind: sortKindTime},
+	"updated_at": {column: "updated_at", kind: sortKindTime},
+	"title":      {column: "title", kind: sortKindString},
+}
+
 type IncidentRepository struct {
 	db *gorm.DB
 }
@@ -13,36 +23,244 @@ func NewIncidentRepository(db *gorm.DB) *IncidentRepository {
 	return &IncidentRepository{db: db}
 }
 
-func (r *IncidentRepository) Create(incident *models.Incident) error {
-	return r.db.Create(incident).Error
+// Create сохраняет инцидент с переводами и задачи events о его создании
+func (r *IncidentRepository) Create(incident *models.Incident, events IncidentEventsFunc) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(incident).Error; err != nil {
+			return err
+		}
+		return createIncidentEvents(tx, events, incident)
+	})
 }
 
 func (r *IncidentRepository) GetAll() ([]models.Incident, error) {
 	var incidents []models.Incident
-	err := r.db.Find(&incidents).Error
+	err := r.db.Preload("Translations").Preload("Attachments").Find(&incidents).Error
 	return incidents, err
 }
 
+// List возвращает страницу инцидентов с учетом фильтров
+func (r *IncidentRepository) List(filter models.IncidentFilter, page models.PageRequest) ([]models.Incident, models.PageInfo, error) {
+	col, err := resolveSort(&page, incidentSortColumns, "created_at")
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	query := applyIncidentFilter(r.db.Model(&models.Incident{}), filter)
+
+	var total int64
+	if page.WithTotal {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, models.PageInfo{}, err
+		}
+	}
+
+	query, err = applyPage(query.Session(&gorm.Session{}), &page, col)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	var incidents []models.Incident
+	if err := query.Preload("Translations").Preload("Attachments").Find(&incidents).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	info := buildPageInfo(&page, col, len(incidents), func(i int) (interface{}, uint) {
+		inc := incidents[i]
+		switch page.SortBy {
+		case "created_at":
+			return inc.CreatedAt, inc.ID
+		case "updated_at":
+			return inc.UpdatedAt, inc.ID
+		case "title":
+			return inc.Title, inc.ID
+		default:
+			return inc.ID, inc.ID
+		}
+	})
+	if len(incidents) > page.Limit {
+		incidents = incidents[:page.Limit]
+	}
+	if page.WithTotal {
+		info.Total = &total
+	}
+
+	return incidents, info, nil
+}
+
+func applyIncidentFilter(query *gorm.DB, filter models.IncidentFilter) *gorm.DB {
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.NeedsReview != nil {
+		query = query.Where("needs_review = ?", *filter.NeedsReview)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.UpdatedFrom != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedFrom)
+	}
+	if filter.UpdatedTo != nil {
+		query = query.Where("updated_at < ?", *filter.UpdatedTo)
+	}
+	if filter.BBox != nil {
+		query = query.Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?",
+			filter.BBox.MinLat, filter.BBox.MaxLat, filter.BBox.MinLng, filter.BBox.MaxLng)
+	}
+	return query
+}
+
+// Search выполняет полнотекстовый поиск (русская и английская конфигурации)
+// и возвращает результаты по убыванию релевантности
+func (r *IncidentRepository) Search(req models.IncidentSearchRequest) ([]models.IncidentSearchResult, int64, error) {
+	query := applyIncidentFilter(r.db.Model(&models.Incident{}), req.Filter).
+		Joins("CROSS JOIN (SELECT websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?) AS q) AS search",
+			req.Query, req.Query).
+		Where("incidents.search_vector @@ search.q")
+
+	var total int64
+	if req.WithTotal {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	type row struct {
+		models.Incident
+		Rank                 float64
+		TitleHighlight       string
+		DescriptionHighlight *string
+	}
+
+	var rows []row
+	err := query.Session(&gorm.Session{}).
+		Select(`incidents.*,
+			ts_rank_cd(incidents.search_vector, search.q) AS rank,
+			ts_headline('russian', incidents.title, search.q, ?) AS title_highlight,
+			ts_headline('russian', incidents.description, search.q, ?) AS description_highlight`,
+			searchHeadlineOptions, searchHeadlineOptions).
+		Order("rank DESC, incidents.id DESC").
+		Limit(req.Limit).
+		Offset(req.Offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]models.IncidentSearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, models.IncidentSearchResult{
+			Incident:             row.Incident,
+			Rank:                 row.Rank,
+			TitleHighlight:       row.TitleHighlight,
+			DescriptionHighlight: row.DescriptionHighlight,
+		})
+	}
+
+	found := make([]*models.Incident, 0, len(results))
+	for i := range results {
+		found = append(found, &results[i].Incident)
+	}
+	if err := r.attachTranslations(found); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
 func (r *IncidentRepository) GetByID(id uint) (*models.Incident, error) {
 	var incident models.Incident
-	err := r.db.First(&incident, id).Error
+	err := r.db.Preload("Translations").Preload("Attachments").First(&incident, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &incident, nil
 }
 
-func (r *IncidentRepository) Update(incident *models.Incident) error {
-	return r.db.Save(incident).Error
+// Update сохраняет инцидент, добавляет/обновляет переводы и ставит задачи events
+// об изменении в одной транзакции
+func (r *IncidentRepository) Update(incident *models.Incident, translations []models.IncidentTranslation, events IncidentEventsFunc) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(incident).Error; err != nil {
+			return err
+		}
+		if err := upsertTranslations(tx, translations); err != nil {
+			return err
+		}
+		return createIncidentEvents(tx, events, incident)
+	})
+}
+
+func upsertTranslations(tx *gorm.DB, translations []models.IncidentTranslation) error {
+	if len(translations) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "incident_id"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "description", "updated_at"}),
+	}).Create(&translations).Error
 }
 
-func (r *IncidentRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Incident{}, id).Error
+// attachTranslations подгружает переводы для инцидентов, полученных без Preload
+func (r *IncidentRepository) attachTranslations(incidents []*models.Incident) error {
+	if len(incidents) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(incidents))
+	for _, incident := range incidents {
+		ids = append(ids, incident.ID)
+	}
+
+	var translations []models.IncidentTranslation
+	if err := r.db.Where("incident_id IN ?", ids).Find(&translations).Error; err != nil {
+		return err
+	}
+
+	byIncident := make(map[uint][]models.IncidentTranslation)
+	for _, t := range translations {
+		byIncident[t.IncidentID] = append(byIncident[t.IncidentID], t)
+	}
+	for _, incident := range incidents {
+		incident.Translations = byIncident[incident.ID]
+	}
+	return nil
+}
+
+// Delete удаляет инцидент и ставит задачи events об удалении. events получает
+// инцидент, прочитанный под блокировкой перед удалением. Возвращает удаленные
+// вместе с инцидентом вложения: их файлы нужно удалить из хранилища после коммита.
+func (r *IncidentRepository) Delete(id uint, events IncidentEventsFunc) ([]models.IncidentAttachment, error) {
+	var attachments []models.IncidentAttachment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var incident models.Incident
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Translations").First(&incident, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("incident_id = ?", id).Find(&attachments).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Incident{}, id).Error; err != nil {
+			return err
+		}
+		return createIncidentEvents(tx, events, &incident)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
 }
 
 // Метод для получения активных инцидентов
 func (r *IncidentRepository) GetActiveIncidents() ([]models.Incident, error) {
 	var incidents []models.Incident
-	err := r.db.Where("is_active = ?", true).Find(&incidents).Error
+	err := r.db.Preload("Translations").Preload("Attachments").Where("is_active = ?", true).Find(&incidents).Error
 	return incidents, err
 }
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type IncidentUpdateRepository struct {
+	db *gorm.DB
+}
+
+func NewIncidentUpdateRepository(db *gorm.DB) *IncidentUpdateRepository {
+	return &IncidentUpdateRepository{db: db}
+}
+
+// Create сохраняет запись хронологии, задачи на оповещение и записи сводок в одной транзакции
+func (r *IncidentUpdateRepository) Create(update *models.IncidentUpdate, batch *models.AlertBatch) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(update).Error; err != nil {
+			return err
+		}
+		if batch == nil || batch.IsEmpty() {
+			return nil
+		}
+		payload := func() models.JSON {
+			return models.JSON{
+				"update": models.JSON{
+					"id":         update.ID,
+					"message":    update.Message,
+					"author":     update.Author,
+					"created_at": update.CreatedAt,
+				},
+			}
+		}
+		for i := range batch.Tasks {
+			batch.Tasks[i].Payload = payload()
+		}
+		for i := range batch.DigestEntries {
+			batch.DigestEntries[i].Payload = payload()
+		}
+		return createAlertBatch(tx, batch)
+	})
+}
+
+func (r *IncidentUpdateRepository) GetByIncident(incidentID uint) ([]models.IncidentUpdate, error) {
+	var updates []models.IncidentUpdate
+	err := r.db.
+		Where("incident_id = ?", incidentID).
+		Order("created_at ASC, id ASC").
+		Find(&updates).Error
+	return updates, err
+}
+
+func (r *IncidentUpdateRepository) GetByID(id uint) (*models.IncidentUpdate, error) {
+	var update models.IncidentUpdate
+	if err := r.db.First(&update, id).Error; err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
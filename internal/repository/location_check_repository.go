@@ -5,6 +5,12 @@ import (
 	"gorm.io/gorm"
 )
 
+var locationCheckSortColumns = map[string]sortColumn{
+	"id":         {column: "id", kind: sortKindInt},
+	"checked_at": {column: "checked_at", kind: sortKindTime},
+	"user_id":    {column: "user_id", kind: sortKindString},
+}
+
 type LocationCheckRepository struct {
 	db *gorm.DB
 }
@@ -23,6 +29,66 @@ func (r *LocationCheckRepository) GetAll() ([]models.LocationCheck, error) {
 	return checks, err
 }
 
+// List возвращает страницу проверок местоположения с учетом фильтров
+func (r *LocationCheckRepository) List(filter models.LocationCheckFilter, page models.PageRequest) ([]models.LocationCheck, models.PageInfo, error) {
+	col, err := resolveSort(&page, locationCheckSortColumns, "checked_at")
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	query := r.db.Model(&models.LocationCheck{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.From != nil {
+		query = query.Where("checked_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("checked_at < ?", *filter.To)
+	}
+	if filter.BBox != nil {
+		query = query.Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?",
+			filter.BBox.MinLat, filter.BBox.MaxLat, filter.BBox.MinLng, filter.BBox.MaxLng)
+	}
+
+	var total int64
+	if page.WithTotal {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, models.PageInfo{}, err
+		}
+	}
+
+	query, err = applyPage(query.Session(&gorm.Session{}), &page, col)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	var checks []models.LocationCheck
+	if err := query.Find(&checks).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	info := buildPageInfo(&page, col, len(checks), func(i int) (interface{}, uint) {
+		check := checks[i]
+		switch page.SortBy {
+		case "checked_at":
+			return check.CheckedAt, check.ID
+		case "user_id":
+			return check.UserID, check.ID
+		default:
+			return check.ID, check.ID
+		}
+	})
+	if len(checks) > page.Limit {
+		checks = checks[:page.Limit]
+	}
+	if page.WithTotal {
+		info.Total = &total
+	}
+
+	return checks, info, nil
+}
+
 func (r *LocationCheckRepository) GetByID(id uint) (*models.LocationCheck, error) {
 	var check models.LocationCheck
 	err := r.db.First(&check, id).Error
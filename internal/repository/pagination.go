@@ -0,0 +1,157 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+type sortKind int
+
+const (
+	sortKindInt sortKind = iota
+	sortKindTime
+	sortKindString
+)
+
+type sortColumn struct {
+	column string
+	kind   sortKind
+}
+
+// cursor - позиция последней записи страницы: значение колонки сортировки и ID
+type cursor struct {
+	SortBy string `json:"s"`
+	Value  string `json:"v"`
+	ID     uint   `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func (s sortColumn) parseValue(raw string) (interface{}, error) {
+	switch s.kind {
+	case sortKindInt:
+		return strconv.ParseUint(raw, 10, 64)
+	case sortKindTime:
+		return time.Parse(time.RFC3339Nano, raw)
+	default:
+		return raw, nil
+	}
+}
+
+func (s sortColumn) formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano)
+	case uint:
+		return strconv.FormatUint(uint64(val), 10)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// resolveSort проверяет параметры сортировки и подставляет значения по умолчанию
+func resolveSort(page *models.PageRequest, columns map[string]sortColumn, defaultSort string) (sortColumn, error) {
+	if page.SortBy == "" {
+		page.SortBy = defaultSort
+	}
+	col, ok := columns[page.SortBy]
+	if !ok {
+		return sortColumn{}, fmt.Errorf("%w: unsupported field %s", ErrInvalidSort, page.SortBy)
+	}
+	switch page.SortOrder {
+	case "":
+		page.SortOrder = models.SortDesc
+	case models.SortAsc, models.SortDesc:
+	default:
+		return sortColumn{}, fmt.Errorf("%w: unsupported order %s", ErrInvalidSort, page.SortOrder)
+	}
+	if page.Limit <= 0 {
+		page.Limit = models.DefaultPageLimit
+	}
+	if page.Limit > models.MaxPageLimit {
+		page.Limit = models.MaxPageLimit
+	}
+	return col, nil
+}
+
+// applyPage добавляет к запросу условие курсора, сортировку и лимит.
+// ID всегда участвует в сортировке, чтобы порядок был стабильным при равных значениях.
+func applyPage(query *gorm.DB, page *models.PageRequest, col sortColumn) (*gorm.DB, error) {
+	op := "<"
+	if page.SortOrder == models.SortAsc {
+		op = ">"
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.SortBy != page.SortBy {
+			return nil, ErrInvalidCursor
+		}
+		if col.column == "id" {
+			query = query.Where("id "+op+" ?", c.ID)
+		} else {
+			value, err := col.parseValue(c.Value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", col.column, op), value, c.ID)
+		}
+	}
+
+	order := col.column + " " + page.SortOrder
+	if col.column != "id" {
+		order += ", id " + page.SortOrder
+	}
+
+	return query.Order(order).Limit(page.Limit + 1), nil
+}
+
+// buildPageInfo обрезает лишнюю запись и формирует курсор следующей страницы
+func buildPageInfo(page *models.PageRequest, col sortColumn, count int, last func(i int) (interface{}, uint)) models.PageInfo {
+	info := models.PageInfo{
+		Limit:     page.Limit,
+		SortBy:    page.SortBy,
+		SortOrder: page.SortOrder,
+	}
+	if count > page.Limit {
+		info.HasMore = true
+		value, id := last(page.Limit - 1)
+		info.NextCursor = encodeCursor(cursor{
+			SortBy: page.SortBy,
+			Value:  col.formatValue(value),
+			ID:     id,
+		})
+	}
+	return info
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"geowarns/internal/geo"
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrClusterNotPending = errors.New("report cluster is already moderated")
+
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// CreateReport сохраняет сообщение и добавляет его в ближайший кластер на модерации
+// той же категории (не дальше radiusMeters, последнее сообщение не старше window).
+// Если подходящего кластера нет, создается новый.
+func (r *ReportRepository) CreateReport(report *models.IncidentReport, radiusMeters float64, window time.Duration) (*models.ReportCluster, error) {
+	var cluster models.ReportCluster
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Сериализуем кластеризацию внутри категории, чтобы два одновременных
+		// сообщения не создали два соседних кластера
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "report_cluster:"+report.Category).Error; err != nil {
+			return err
+		}
+
+		dLat, dLng := geo.DegreesAround(report.Latitude, radiusMeters)
+		var candidates []models.ReportCluster
+		err := tx.
+			Where("status = ? AND category = ? AND last_report_at >= ?",
+				models.ReportStatusPending, report.Category, report.CreatedAt.Add(-window)).
+			Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?",
+				report.Latitude-dLat, report.Latitude+dLat, report.Longitude-dLng, report.Longitude+dLng).
+			Find(&candidates).Error
+		if err != nil {
+			return err
+		}
+
+		best := -1
+		bestDistance := radiusMeters
+		for i, c := range candidates {
+			d := geo.DistanceMeters(report.Latitude, report.Longitude, c.Latitude, c.Longitude)
+			if d <= bestDistance {
+				best, bestDistance = i, d
+			}
+		}
+
+		if best >= 0 {
+			cluster = candidates[best]
+			// Центр кластера - среднее координат всех сообщений
+			n := float64(cluster.ReportCount)
+			cluster.Latitude = (cluster.Latitude*n + report.Latitude) / (n + 1)
+			cluster.Longitude = (cluster.Longitude*n + report.Longitude) / (n + 1)
+			cluster.ReportCount++
+			cluster.LastReportAt = report.CreatedAt
+			if err := tx.Model(&cluster).Updates(map[string]interface{}{
+				"latitude":       cluster.Latitude,
+				"longitude":      cluster.Longitude,
+				"report_count":   cluster.ReportCount,
+				"last_report_at": cluster.LastReportAt,
+			}).Error; err != nil {
+				return err
+			}
+		} else {
+			cluster = models.ReportCluster{
+				Category:      report.Category,
+				Latitude:      report.Latitude,
+				Longitude:     report.Longitude,
+				ReportCount:   1,
+				Status:        models.ReportStatusPending,
+				FirstReportAt: report.CreatedAt,
+				LastReportAt:  report.CreatedAt,
+			}
+			if err := tx.Create(&cluster).Error; err != nil {
+				return err
+			}
+		}
+
+		report.ClusterID = cluster.ID
+		return tx.Create(report).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &cluster, nil
+}
+
+func (r *ReportRepository) GetReportByID(id uint) (*models.IncidentReport, error) {
+	var report models.IncidentReport
+	if err := r.db.First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListClusters возвращает очередь модерации: сначала кластеры с большим числом сообщений
+func (r *ReportRepository) ListClusters(status string, limit, offset int) ([]models.ReportCluster, int64, error) {
+	query := r.db.Model(&models.ReportCluster{}).Where("status = ?", status)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var clusters []models.ReportCluster
+	err := query.Session(&gorm.Session{}).
+		Order("report_count DESC, last_report_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&clusters).Error
+	return clusters, total, err
+}
+
+func (r *ReportRepository) GetCluster(id uint) (*models.ReportCluster, error) {
+	var cluster models.ReportCluster
+	err := r.db.
+		Preload("Reports", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC, id ASC")
+		}).
+		First(&cluster, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+// Approve создает инцидент из кластера
+func (r *ReportRepository) Approve(clusterID uint, incident *models.Incident, moderator *string, events IncidentEventsFunc) (*models.ReportCluster, error) {
+	return r.moderate(clusterID, func(tx *gorm.DB, cluster *models.ReportCluster) (string, *uint, error) {
+		if err := tx.Omit(clause.Associations).Create(incident).Error; err != nil {
+			return "", nil, err
+		}
+		if err := createIncidentEvents(tx, events, incident); err != nil {
+			return "", nil, err
+		}
+		cluster.Moderator = moderator
+		return models.ReportStatusApproved, &incident.ID, nil
+	})
+}
+
+// Merge присоединяет кластер к существующему инциденту
+func (r *ReportRepository) Merge(clusterID, incidentID uint, moderator *string) (*models.ReportCluster, error) {
+	return r.moderate(clusterID, func(tx *gorm.DB, cluster *models.ReportCluster) (string, *uint, error) {
+		var incident models.Incident
+		if err := tx.Select("id").First(&incident, incidentID).Error; err != nil {
+			return "", nil, err
+		}
+		cluster.Moderator = moderator
+		return models.ReportStatusMerged, &incident.ID, nil
+	})
+}
+
+func (r *ReportRepository) Reject(clusterID uint, moderator, reason *string) (*models.ReportCluster, error) {
+	return r.moderate(clusterID, func(tx *gorm.DB, cluster *models.ReportCluster) (string, *uint, error) {
+		cluster.Moderator = moderator
+		cluster.Reason = reason
+		return models.ReportStatusRejected, nil, nil
+	})
+}
+
+// moderate блокирует кластер, проверяет что он еще на модерации и переводит
+// кластер и все его сообщения в итоговый статус
+func (r *ReportRepository) moderate(
+	clusterID uint,
+	decide func(tx *gorm.DB, cluster *models.ReportCluster) (string, *uint, error),
+) (*models.ReportCluster, error) {
+	var cluster models.ReportCluster
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cluster, clusterID).Error; err != nil {
+			return err
+		}
+		if cluster.Status != models.ReportStatusPending {
+			return ErrClusterNotPending
+		}
+
+		status, incidentID, err := decide(tx, &cluster)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		cluster.Status = status
+		cluster.IncidentID = incidentID
+		cluster.ModeratedAt = &now
+
+		if err := tx.Model(&cluster).Updates(map[string]interface{}{
+			"status":       cluster.Status,
+			"incident_id":  cluster.IncidentID,
+			"moderator":    cluster.Moderator,
+			"reason":       cluster.Reason,
+			"moderated_at": cluster.ModeratedAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.IncidentReport{}).
+			Where("cluster_id = ?", cluster.ID).
+			Updates(map[string]interface{}{
+				"status":       status,
+				"incident_id":  incidentID,
+				"moderated_at": now,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetCluster(cluster.ID)
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrUnknownRetentionTable = errors.New("unknown retention table")
+
+// retentionTable описывает таблицу, которую можно очищать: по какому столбцу
+// считается возраст строки, какие статусы можно удалять (nil - у таблицы нет
+// статуса) и что удалить вместе со строками (dependents выполняются в одном
+// запросе с удалением и видят удаляемые id в doomed)
+type retentionTable struct {
+	timeColumn string
+	statuses   []string
+	dependents []string
+}
+
+var retentionTables = map[string]retentionTable{
+	"webhook_tasks": {
+		// Возраст - время последнего изменения, то есть завершения задачи. Задачи в
+		// pending и processing не удаляются никогда.
+		timeColumn: "updated_at",
+		statuses:   []string{"completed", "failed", "discarded"},
+		// Иначе записи уже отправленной сводки вернутся в следующую
+		dependents: []string{"DELETE FROM webhook_digest_entries WHERE digest_task_id IN (SELECT id FROM doomed)"},
+	},
+	"webhook_delivery_attempts": {timeColumn: "created_at"},
+	"location_checks":           {timeColumn: "checked_at"},
+}
+
+// RetentionTables возвращает таблицы, для которых можно задать правило очистки
+func RetentionTables() []string {
+	return []string{"webhook_tasks", "webhook_delivery_attempts", "location_checks"}
+}
+
+// ValidateRetentionPolicy проверяет, что таблица поддерживается, а статусы допустимы
+func ValidateRetentionPolicy(policy models.RetentionPolicy) error {
+	spec, ok := retentionTables[policy.Table]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRetentionTable, policy.Table)
+	}
+	if spec.statuses == nil && len(policy.Statuses) > 0 {
+		return fmt.Errorf("table %s has no status column", policy.Table)
+	}
+	for _, status := range policy.Statuses {
+		if !containsString(spec.statuses, status) {
+			return fmt.Errorf("status %q can't be deleted from %s, allowed: %s",
+				status, policy.Table, strings.Join(spec.statuses, ", "))
+		}
+	}
+	return nil
+}
+
+// RetentionScope - строки, подпадающие под правило очистки. Граница по MaxRows
+// вычисляется один раз, поэтому строки, добавленные во время очистки, не удаляются.
+type RetentionScope struct {
+	table string
+	where string
+	args  []interface{}
+}
+
+type RetentionRepository struct {
+	db *gorm.DB
+}
+
+func NewRetentionRepository(db *gorm.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// Scope возвращает строки, которые правило удаляет на момент now, или nil, если таких нет
+func (r *RetentionRepository) Scope(policy models.RetentionPolicy, now time.Time) (*RetentionScope, error) {
+	if err := ValidateRetentionPolicy(policy); err != nil {
+		return nil, err
+	}
+	spec := retentionTables[policy.Table]
+
+	var base []string
+	var baseArgs []interface{}
+	statuses := policy.Statuses
+	if spec.statuses != nil && len(statuses) == 0 {
+		statuses = spec.statuses
+	}
+	if len(statuses) > 0 {
+		base = append(base, "status IN ?")
+		baseArgs = append(baseArgs, statuses)
+	}
+
+	var bounds []string
+	var boundArgs []interface{}
+	if policy.MaxAge > 0 {
+		bounds = append(bounds, spec.timeColumn+" < ?")
+		boundArgs = append(boundArgs, now.Add(-policy.MaxAge))
+	}
+	if policy.MaxRows > 0 {
+		// Последняя сохраняемая строка: все, что старше нее, удаляется
+		var last []struct {
+			At time.Time
+			ID uint
+		}
+		query := r.db.Table(policy.Table).
+			Select(spec.timeColumn + " AS at, id").
+			Where(spec.timeColumn + " IS NOT NULL")
+		if len(base) > 0 {
+			query = query.Where(strings.Join(base, " AND "), baseArgs...)
+		}
+		err := query.
+			Order(spec.timeColumn + " DESC, id DESC").
+			Offset(int(policy.MaxRows - 1)).
+			Limit(1).
+			Scan(&last).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(last) > 0 {
+			bounds = append(bounds, "("+spec.timeColumn+", id) < (?, ?)")
+			boundArgs = append(boundArgs, last[0].At, last[0].ID)
+		}
+	}
+	if len(bounds) == 0 {
+		return nil, nil
+	}
+
+	where := "(" + strings.Join(bounds, " OR ") + ")"
+	if len(base) > 0 {
+		where = strings.Join(base, " AND ") + " AND " + where
+	}
+	return &RetentionScope{
+		table: policy.Table,
+		where: where,
+		args:  append(baseArgs, boundArgs...),
+	}, nil
+}
+
+// Count возвращает число строк в scope
+func (r *RetentionRepository) Count(scope *RetentionScope) (int64, error) {
+	var count int64
+	err := r.db.Table(scope.table).Where(scope.where, scope.args...).Count(&count).Error
+	return count, err
+}
+
+// DeleteBatch удаляет до limit строк из scope (самые старые по id) и возвращает их число.
+// Короткие пачки не держат блокировки подолгу.
+func (r *RetentionRepository) DeleteBatch(scope *RetentionScope, limit int) (int64, error) {
+	spec := retentionTables[scope.table]
+
+	var sql strings.Builder
+	fmt.Fprintf(&sql, "WITH doomed AS (SELECT id FROM %s WHERE %s ORDER BY id LIMIT ?)", scope.table, scope.where)
+	for i, dependent := range spec.dependents {
+		fmt.Fprintf(&sql, ", dependent_%d AS (%s)", i, dependent)
+	}
+	fmt.Fprintf(&sql, " DELETE FROM %s WHERE id IN (SELECT id FROM doomed)", scope.table)
+
+	args := append(append([]interface{}{}, scope.args...), limit)
+	result := r.db.Exec(sql.String(), args...)
+	return result.RowsAffected, result.Error
+}
+
+func (r *RetentionRepository) SaveRun(run *models.RetentionRun) error {
+	return r.db.Create(run).Error
+}
+
+// ListRuns возвращает последние запуски очистки, table - фильтр по таблице
+func (r *RetentionRepository) ListRuns(table string, limit int) ([]models.RetentionRun, error) {
+	var runs []models.RetentionRun
+	query := r.db.Order("started_at DESC, id DESC").Limit(limit)
+	if table != "" {
+		query = query.Where("table_name = ?", table)
+	}
+	err := query.Find(&runs).Error
+	return runs, err
+}
+
+// GetStats возвращает итоги очистки по таблицам (без пробных запусков)
+func (r *RetentionRepository) GetStats() ([]models.RetentionTableStats, error) {
+	var stats []models.RetentionTableStats
+	err := r.db.Raw(`
+		SELECT DISTINCT ON (table_name)
+			table_name AS "table",
+			COUNT(*) OVER w AS runs,
+			(SUM(affected_rows) OVER w)::bigint AS deleted_rows,
+			started_at AS last_run_at,
+			affected_rows AS last_deleted,
+			error AS last_error
+		FROM retention_runs
+		WHERE NOT dry_run
+		WINDOW w AS (PARTITION BY table_name)
+		ORDER BY table_name, started_at DESC, id DESC`).
+		Scan(&stats).Error
+	return stats, err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
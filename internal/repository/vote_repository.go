@@ -0,0 +1,129 @@
+package database
+
+import (
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VoteRepository struct {
+	db *gorm.DB
+}
+
+func NewVoteRepository(db *gorm.DB) *VoteRepository {
+	return &VoteRepository{db: db}
+}
+
+// CastVote сохраняет голос пользователя, пересчитывает итоги по инциденту и сохраняет
+// решение evaluate (уверенность, пометка на проверку, автоматическое отключение).
+// Инцидент блокируется на время пересчета, чтобы одновременные голоса не потеряли решение.
+// При автоматическом отключении ставятся задачи deactivated.
+func (r *VoteRepository) CastVote(
+	vote *models.IncidentVote,
+	evaluate func(summary *models.IncidentVoteSummary),
+	deactivated IncidentEventsFunc,
+) (*models.IncidentVoteSummary, error) {
+	var summary *models.IncidentVoteSummary
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var incident models.Incident
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "is_active", "needs_review").
+			First(&incident, vote.IncidentID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "incident_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"vote", "latitude", "longitude", "distance_meters", "weight", "updated_at"}),
+		}).Create(vote).Error; err != nil {
+			return err
+		}
+
+		var err error
+		summary, err = summarize(tx, vote.IncidentID)
+		if err != nil {
+			return err
+		}
+		summary.IsActive = incident.IsActive
+		summary.NeedsReview = incident.NeedsReview
+
+		evaluate(summary)
+
+		updates := map[string]interface{}{
+			"confidence":   summary.Confidence,
+			"needs_review": summary.NeedsReview,
+		}
+		deactivate := summary.AutoDeactivated && incident.IsActive
+		if deactivate {
+			summary.IsActive = false
+			updates["is_active"] = false
+			updates["updated_at"] = gorm.Expr("NOW()")
+		}
+
+		if err := tx.Model(&models.Incident{}).Where("id = ?", incident.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if !deactivate {
+			return nil
+		}
+
+		var full models.Incident
+		if err := tx.First(&full, incident.ID).Error; err != nil {
+			return err
+		}
+		return createIncidentEvents(tx, deactivated, &full)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (r *VoteRepository) GetSummary(incidentID uint) (*models.IncidentVoteSummary, error) {
+	var incident models.Incident
+	if err := r.db.Select("id", "is_active", "needs_review", "confidence").First(&incident, incidentID).Error; err != nil {
+		return nil, err
+	}
+
+	summary, err := summarize(r.db, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	summary.IsActive = incident.IsActive
+	summary.NeedsReview = incident.NeedsReview
+	if incident.Confidence != nil {
+		summary.Confidence = *incident.Confidence
+	}
+	return summary, nil
+}
+
+func (r *VoteRepository) GetVotes(incidentID uint) ([]models.IncidentVote, error) {
+	var votes []models.IncidentVote
+	err := r.db.
+		Where("incident_id = ?", incidentID).
+		Order("updated_at DESC").
+		Find(&votes).Error
+	return votes, err
+}
+
+func summarize(db *gorm.DB, incidentID uint) (*models.IncidentVoteSummary, error) {
+	summary := &models.IncidentVoteSummary{IncidentID: incidentID}
+	err := db.Raw(`
+		SELECT
+			COUNT(*) FILTER (WHERE vote = ?) AS confirmations,
+			COUNT(*) FILTER (WHERE vote = ?) AS disputes,
+			COALESCE(SUM(weight) FILTER (WHERE vote = ?), 0) AS confirm_weight,
+			COALESCE(SUM(weight) FILTER (WHERE vote = ?), 0) AS dispute_weight
+		FROM incident_votes
+		WHERE incident_id = ?`,
+		models.VoteConfirm, models.VoteDispute, models.VoteConfirm, models.VoteDispute, incidentID).
+		Row().
+		Scan(&summary.Confirmations, &summary.Disputes, &summary.ConfirmWeight, &summary.DisputeWeight)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CircuitFailure - параметры учета неудачной отправки
+type CircuitFailure struct {
+	Error string
+	// Threshold - после скольких неудач подряд выключатель размыкается
+	Threshold int
+	// OpenFor возвращает паузу после trips-го размыкания
+	OpenFor func(trips int) time.Duration
+	// DisableAfter - через сколько после начала серии неудач получатель отключается (0 - никогда)
+	DisableAfter time.Duration
+}
+
+type WebhookCircuitRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookCircuitRepository(db *gorm.DB) *WebhookCircuitRepository {
+	return &WebhookCircuitRepository{db: db}
+}
+
+// Get возвращает состояние выключателя; для получателя без записи - замкнутый выключатель
+func (r *WebhookCircuitRepository) Get(subscriberID uint) (*models.WebhookCircuit, error) {
+	var circuit models.WebhookCircuit
+	err := r.db.First(&circuit, "subscriber_id = ?", subscriberID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.WebhookCircuit{SubscriberID: subscriberID, State: models.CircuitClosed}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &circuit, nil
+}
+
+// TryProbe переводит выключатель, пауза которого истекла, в half_open до probeUntil.
+// Возвращает true, если пробную отправку выполняет вызывающий.
+func (r *WebhookCircuitRepository) TryProbe(subscriberID uint, probeUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.WebhookCircuit{}).
+		Where("subscriber_id = ? AND state IN ? AND retry_at <= NOW()",
+			subscriberID, []string{models.CircuitOpen, models.CircuitHalfOpen}).
+		Updates(map[string]interface{}{
+			"state":      models.CircuitHalfOpen,
+			"retry_at":   probeUntil,
+			"updated_at": gorm.Expr("NOW()"),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordSuccess замыкает выключатель и сбрасывает серию неудач
+func (r *WebhookCircuitRepository) RecordSuccess(subscriberID uint) error {
+	return r.db.Model(&models.WebhookCircuit{}).
+		Where("subscriber_id = ? AND (state <> ? OR consecutive_failures > 0)", subscriberID, models.CircuitClosed).
+		Updates(map[string]interface{}{
+			"state":                models.CircuitClosed,
+			"consecutive_failures": 0,
+			"failing_since":        nil,
+			"retry_at":             nil,
+			"disabled_at":          nil,
+			"updated_at":           gorm.Expr("NOW()"),
+		}).Error
+}
+
+// RecordFailure учитывает неудачную отправку: размыкает выключатель после Threshold
+// неудач подряд или при неудачной пробной отправке и отключает получателя, если
+// серия неудач длится дольше DisableAfter. Возвращает новое состояние, а также
+// признаки того, что выключатель разомкнулся и получатель отключен этим вызовом.
+func (r *WebhookCircuitRepository) RecordFailure(subscriberID uint, failure CircuitFailure) (*models.WebhookCircuit, bool, bool, error) {
+	var circuit models.WebhookCircuit
+	var opened, disabled bool
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.WebhookCircuit{SubscriberID: subscriberID, State: models.CircuitClosed}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&circuit, "subscriber_id = ?", subscriberID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		circuit.ConsecutiveFailures++
+		circuit.LastError = &failure.Error
+		if circuit.FailingSince == nil {
+			circuit.FailingSince = &now
+		}
+
+		// Неудачи отправок, начатых до размыкания, паузу не продлевают
+		if circuit.State == models.CircuitHalfOpen ||
+			(circuit.State == models.CircuitClosed && circuit.ConsecutiveFailures >= failure.Threshold) {
+			opened = true
+			circuit.Trips++
+			retryAt := now.Add(failure.OpenFor(circuit.Trips))
+			circuit.State = models.CircuitOpen
+			circuit.OpenedAt = &now
+			circuit.RetryAt = &retryAt
+		}
+
+		if failure.DisableAfter > 0 && circuit.DisabledAt == nil && now.Sub(*circuit.FailingSince) >= failure.DisableAfter {
+			circuit.DisabledAt = &now
+			disabled = true
+			err := tx.Model(&models.WebhookSubscriber{}).
+				Where("id = ?", subscriberID).
+				UpdateColumn("enabled", false).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Save(&circuit).Error
+	})
+	if err != nil {
+		return nil, false, false, err
+	}
+	return &circuit, opened, disabled, nil
+}
+
+// Reset замыкает выключатель, например при ручном включении получателя
+func (r *WebhookCircuitRepository) Reset(subscriberID uint) error {
+	return r.db.Where("subscriber_id = ?", subscriberID).Delete(&models.WebhookCircuit{}).Error
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRateRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRateRepository(db *gorm.DB) *WebhookRateRepository {
+	return &WebhookRateRepository{db: db}
+}
+
+// Reserve резервирует одну отправку получателю в общем token bucket со скоростью
+// rate запросов в секунду и запасом burst. Возвращает, сколько нужно подождать
+// до отправки (0 - можно сразу). Пополнение считается по времени базы данных,
+// поэтому ограничение соблюдается всеми репликами вместе.
+func (r *WebhookRateRepository) Reserve(subscriberID uint, rate, burst float64) (time.Duration, error) {
+	var tokens float64
+	err := r.db.Raw(`
+		INSERT INTO webhook_rate_buckets AS b (subscriber_id, tokens, refilled_at)
+		VALUES (?, ? - 1, NOW())
+		ON CONFLICT (subscriber_id) DO UPDATE SET
+			tokens = LEAST(?, b.tokens + EXTRACT(EPOCH FROM NOW() - b.refilled_at) * ?) - 1,
+			refilled_at = NOW()
+		RETURNING tokens`,
+		subscriberID, burst, burst, rate).
+		Scan(&tokens).Error
+	if err != nil {
+		return 0, err
+	}
+	if tokens >= 0 {
+		return 0, nil
+	}
+	return time.Duration(math.Ceil(-tokens / rate * float64(time.Second))), nil
+}
+
+// Cancel возвращает зарезервированную отправку, которая не будет выполнена
+func (r *WebhookRateRepository) Cancel(subscriberID uint) error {
+	return r.db.Exec(`UPDATE webhook_rate_buckets SET tokens = tokens + 1 WHERE subscriber_id = ?`, subscriberID).Error
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"geowarns/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type WebhookSubscriberRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookSubscriberRepository(db *gorm.DB) *WebhookSubscriberRepository {
+	return &WebhookSubscriberRepository{db: db}
+}
+
+func (r *WebhookSubscriberRepository) Create(subscriber *models.WebhookSubscriber) error {
+	if err := r.db.Create(subscriber).Error; err != nil {
+		return err
+	}
+	subscriber.HasSecret = subscriber.Secret != ""
+	return nil
+}
+
+func (r *WebhookSubscriberRepository) GetAll() ([]models.WebhookSubscriber, error) {
+	var subscribers []models.WebhookSubscriber
+	err := r.db.Order("id ASC").Find(&subscribers).Error
+	return subscribers, err
+}
+
+func (r *WebhookSubscriberRepository) GetEnabled() ([]models.WebhookSubscriber, error) {
+	var subscribers []models.WebhookSubscriber
+	err := r.db.Where("enabled = ?", true).Order("id ASC").Find(&subscribers).Error
+	return subscribers, err
+}
+
+func (r *WebhookSubscriberRepository) GetByID(id uint) (*models.WebhookSubscriber, error) {
+	var subscriber models.WebhookSubscriber
+	if err := r.db.First(&subscriber, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscriber, nil
+}
+
+func (r *WebhookSubscriberRepository) Update(subscriber *models.WebhookSubscriber) error {
+	if err := r.db.Save(subscriber).Error; err != nil {
+		return err
+	}
+	subscriber.HasSecret = subscriber.Secret != ""
+	return nil
+}
+
+// Delete удаляет получателя вместе с его задачами
+func (r *WebhookSubscriberRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.WebhookSubscriber{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *WebhookSubscriberRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.WebhookSubscriber{}).Count(&count).Error
+	return count, err
+}
@@ -1,11 +1,33 @@
 package database
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
 	"geowarns/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrLeaseLost - задачу уже забрал другой обработчик (аренда истекла)
+	ErrLeaseLost = errors.New("task lease lost")
+	// ErrTaskNotFailed - действие доступно только для задач в статусе failed
+	ErrTaskNotFailed = errors.New("task is not failed")
+	// ErrTaskStatusConflict - действие недоступно для текущего статуса задачи
+	ErrTaskStatusConflict = errors.New("action is not allowed for task status")
 )
 
+var webhookTaskSortColumns = map[string]sortColumn{
+	"id":         {column: "id", kind: sortKindInt},
+	"created_at": {column: "created_at", kind: sortKindTime},
+	"updated_at": {column: "updated_at", kind: sortKindTime},
+}
+
 type WebhookTaskRepository struct {
 	db *gorm.DB
 }
@@ -18,16 +40,238 @@ func (r *WebhookTaskRepository) Create(task *models.WebhookTask) error {
 	return r.db.Create(task).Error
 }
 
+// CreateAlertTasks ставит задачи оповещения пользователя по инцидентам проверки
+// локации. Инцидент считается новым для пользователя, если оповещения о нем не было
+// в течение cooldown или инцидент с тех пор существенно изменился (другой fingerprint).
+// Проверка и запись выполняются UPSERT-ом в одной транзакции с созданием задач, поэтому
+// из нескольких одновременных проверок оповещение поставит только одна. При cooldown <= 0
+// новыми считаются все инциденты. build получает новые инциденты и возвращает задачи
+// и записи для сводок.
+func (r *WebhookTaskRepository) CreateAlertTasks(
+	userID string,
+	fingerprints map[uint]string,
+	cooldown time.Duration,
+	build func(fresh map[uint]bool) models.AlertBatch,
+) (models.AlertBatch, error) {
+	var batch models.AlertBatch
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids := make([]uint, 0, len(fingerprints))
+		for id := range fingerprints {
+			ids = append(ids, id)
+		}
+		// Одинаковый порядок блокировок исключает взаимоблокировки параллельных проверок
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		fresh := make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			if cooldown <= 0 {
+				fresh[id] = true
+				continue
+			}
+			var claimed []string
+			err := tx.Raw(`
+				INSERT INTO user_incident_notifications (user_id, incident_id, fingerprint, notified_count, last_notified_at)
+				VALUES (?, ?, ?, 1, NOW())
+				ON CONFLICT (user_id, incident_id) DO UPDATE SET
+					fingerprint = EXCLUDED.fingerprint,
+					notified_count = user_incident_notifications.notified_count + 1,
+					last_notified_at = NOW()
+				WHERE user_incident_notifications.last_notified_at <= NOW() - make_interval(secs => ?)
+					OR user_incident_notifications.fingerprint <> EXCLUDED.fingerprint
+				RETURNING user_id`,
+				userID, id, fingerprints[id], cooldown.Seconds()).
+				Scan(&claimed).Error
+			if err != nil {
+				return err
+			}
+			fresh[id] = len(claimed) > 0
+		}
+
+		batch = build(fresh)
+		return createAlertBatch(tx, &batch)
+	})
+
+	return batch, err
+}
+
+// IncidentEventsFunc строит задачи о событии инцидента. Вызывается внутри транзакции
+// изменения инцидента, поэтому событие ставится в очередь только вместе с изменением.
+type IncidentEventsFunc func(incident *models.Incident) (*models.AlertBatch, error)
+
+func createIncidentEvents(tx *gorm.DB, events IncidentEventsFunc, incident *models.Incident) error {
+	if events == nil {
+		return nil
+	}
+	batch, err := events(incident)
+	if err != nil || batch == nil {
+		return err
+	}
+	return createAlertBatch(tx, batch)
+}
+
+// createAlertBatch сохраняет задачи и записи сводок в транзакции tx
+func createAlertBatch(tx *gorm.DB, batch *models.AlertBatch) error {
+	if len(batch.Tasks) > 0 {
+		if err := tx.Create(&batch.Tasks).Error; err != nil {
+			return err
+		}
+	}
+	if len(batch.DigestEntries) > 0 {
+		if err := tx.Create(&batch.DigestEntries).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPendingTasks возвращает задачи, время следующей попытки которых уже наступило
 func (r *WebhookTaskRepository) GetPendingTasks() ([]models.WebhookTask, error) {
 	var tasks []models.WebhookTask
 	err := r.db.
-		Where("status = ?", "pending").
-		Order("created_at ASC").
+		Where("status = ? AND next_attempt <= NOW()", "pending").
+		Order("next_attempt ASC, id ASC").
 		Limit(100).
 		Find(&tasks).Error
 	return tasks, err
 }
 
+// ClaimTasks атомарно забирает до limit готовых к отправке задач: переводит их в
+// processing и выдает аренду владельцу owner на время lease. Задачи в processing
+// с истекшей арендой (обработчик упал) забираются повторно. Задачи отключенных
+// получателей и получателей с разомкнутым выключателем остаются в очереди, а
+// получателю с max_in_flight выдается не больше задач, чем у него свободных мест.
+// Строки выбираются через FOR UPDATE SKIP LOCKED, поэтому реплики забирают задачи
+// параллельно. Число отправок в полете считается по всем репликам, так что для
+// получателей с max_in_flight оно перепроверяется под advisory lock получателя.
+func (r *WebhookTaskRepository) ClaimTasks(owner string, limit int, lease time.Duration) ([]models.WebhookTask, error) {
+	var tasks []models.WebhookTask
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var candidates []claimCandidate
+		err := tx.Raw(`
+			WITH in_flight AS (
+				SELECT subscriber_id, COUNT(*) AS n FROM webhook_tasks
+				WHERE status = 'processing' AND locked_until >= NOW() AND subscriber_id IS NOT NULL
+				GROUP BY subscriber_id
+			),
+			ready AS (
+				SELECT t.id, s.max_in_flight, COALESCE(f.n, 0) AS in_flight,
+					ROW_NUMBER() OVER (PARTITION BY t.subscriber_id ORDER BY t.next_attempt ASC, t.id ASC) AS rn
+				FROM webhook_tasks t
+				LEFT JOIN webhook_subscribers s ON s.id = t.subscriber_id
+				LEFT JOIN webhook_endpoint_circuits c ON c.subscriber_id = t.subscriber_id
+				LEFT JOIN in_flight f ON f.subscriber_id = t.subscriber_id
+				WHERE ((t.status = 'pending' AND t.next_attempt <= NOW())
+						OR (t.status = 'processing' AND t.locked_until < NOW()))
+					AND (s.id IS NULL OR s.enabled)
+					AND (c.subscriber_id IS NULL OR c.state = 'closed' OR c.retry_at <= NOW())
+			)
+			SELECT t.id, t.subscriber_id, r.max_in_flight
+			FROM webhook_tasks t
+			JOIN ready r ON r.id = t.id
+			WHERE (r.max_in_flight IS NULL OR r.rn + r.in_flight <= r.max_in_flight)
+				-- повторная проверка на случай, если задачу успел завершить прежний владелец
+				AND ((t.status = 'pending' AND t.next_attempt <= NOW())
+					OR (t.status = 'processing' AND t.locked_until < NOW()))
+			ORDER BY t.next_attempt ASC, t.id ASC
+			LIMIT ?
+			FOR UPDATE OF t SKIP LOCKED`, limit).
+			Scan(&candidates).Error
+		if err != nil {
+			return err
+		}
+
+		ids, err := r.fitInFlight(tx, candidates)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		return tx.Raw(`
+			UPDATE webhook_tasks SET
+				status = 'processing',
+				locked_by = ?,
+				locked_until = NOW() + make_interval(secs => ?),
+				updated_at = NOW()
+			WHERE id IN ?
+			RETURNING *`,
+			owner, lease.Seconds(), ids).
+			Scan(&tasks).Error
+	})
+	return tasks, err
+}
+
+// claimCandidate - задача, заблокированная ClaimTasks до выдачи аренды
+type claimCandidate struct {
+	ID           uint
+	SubscriberID *uint
+	MaxInFlight  *int
+}
+
+// fitInFlight оставляет из кандидатов (в порядке очереди) те, что помещаются в
+// max_in_flight своего получателя. Оценка в ClaimTasks сделана без блокировок, поэтому
+// число отправок в полете пересчитывается под advisory lock получателя: он держится
+// до конца транзакции, и параллельная реплика увидит уже выданные аренды. Блокировки
+// берутся по возрастанию ID получателя, чтобы реплики не ждали друг друга по кругу.
+func (r *WebhookTaskRepository) fitInFlight(tx *gorm.DB, candidates []claimCandidate) ([]uint, error) {
+	limited := make(map[uint]int)
+	var subscriberIDs []uint
+	for _, c := range candidates {
+		if c.MaxInFlight == nil || c.SubscriberID == nil {
+			continue
+		}
+		if _, ok := limited[*c.SubscriberID]; !ok {
+			subscriberIDs = append(subscriberIDs, *c.SubscriberID)
+		}
+		limited[*c.SubscriberID] = *c.MaxInFlight
+	}
+	sort.Slice(subscriberIDs, func(i, j int) bool { return subscriberIDs[i] < subscriberIDs[j] })
+
+	for _, subscriberID := range subscriberIDs {
+		key := "webhook_claim:" + strconv.FormatUint(uint64(subscriberID), 10)
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+			return nil, err
+		}
+		var inFlight int64
+		err := tx.Model(&models.WebhookTask{}).
+			Where("subscriber_id = ? AND status = 'processing' AND locked_until >= NOW()", subscriberID).
+			Count(&inFlight).Error
+		if err != nil {
+			return nil, err
+		}
+		limited[subscriberID] -= int(inFlight)
+	}
+
+	ids := make([]uint, 0, len(candidates))
+	for _, c := range candidates {
+		if c.MaxInFlight != nil && c.SubscriberID != nil {
+			if limited[*c.SubscriberID] <= 0 {
+				continue
+			}
+			limited[*c.SubscriberID]--
+		}
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+// finishClaimed обновляет задачу, только если ее аренда все еще принадлежит owner
+func (r *WebhookTaskRepository) finishClaimed(taskID uint, owner string, updates map[string]interface{}) error {
+	updates["locked_by"] = nil
+	updates["locked_until"] = nil
+	updates["updated_at"] = gorm.Expr("NOW()")
+
+	result := r.db.
+		Model(&models.WebhookTask{}).
+		Where("id = ? AND status = 'processing' AND locked_by = ?", taskID, owner).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
 
 func (r *WebhookTaskRepository) UpdateStatus(taskID uint, status string) error {
 	return r.db.
@@ -39,9 +283,248 @@ func (r *WebhookTaskRepository) UpdateStatus(taskID uint, status string) error {
 		}).Error
 }
 
+func (r *WebhookTaskRepository) MarkCompleted(taskID uint, owner string, attempts int) error {
+	return r.finishClaimed(taskID, owner, map[string]interface{}{
+		"status":     "completed",
+		"attempts":   attempts,
+		"last_error": nil,
+	})
+}
+
+// ScheduleRetry возвращает задачу в pending и переносит следующую попытку на nextAttempt
+func (r *WebhookTaskRepository) ScheduleRetry(taskID uint, owner string, attempts int, nextAttempt time.Time, lastError string) error {
+	return r.finishClaimed(taskID, owner, map[string]interface{}{
+		"status":       "pending",
+		"attempts":     attempts,
+		"next_attempt": nextAttempt,
+		"last_error":   lastError,
+	})
+}
+
+// Postpone возвращает задачу в pending, не засчитывая попытку: отправка не
+// выполнялась, потому что получатель временно недоступен
+func (r *WebhookTaskRepository) Postpone(taskID uint, owner string, nextAttempt time.Time) error {
+	return r.finishClaimed(taskID, owner, map[string]interface{}{
+		"status":       "pending",
+		"next_attempt": nextAttempt,
+	})
+}
+
+func (r *WebhookTaskRepository) MarkFailed(taskID uint, owner string, attempts int, lastError string) error {
+	return r.finishClaimed(taskID, owner, map[string]interface{}{
+		"status":     "failed",
+		"attempts":   attempts,
+		"last_error": lastError,
+	})
+}
+
+// List возвращает страницу задач, подходящих под фильтр
+func (r *WebhookTaskRepository) List(filter models.WebhookTaskFilter, page models.PageRequest) ([]models.WebhookTask, models.PageInfo, error) {
+	col, err := resolveSort(&page, webhookTaskSortColumns, "updated_at")
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	query := applyTaskFilter(r.db.Model(&models.WebhookTask{}), filter)
+
+	var total int64
+	if page.WithTotal {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, models.PageInfo{}, err
+		}
+	}
+
+	query, err = applyPage(query.Session(&gorm.Session{}), &page, col)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	var tasks []models.WebhookTask
+	if err := query.Find(&tasks).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	info := buildPageInfo(&page, col, len(tasks), func(i int) (interface{}, uint) {
+		task := tasks[i]
+		switch page.SortBy {
+		case "created_at":
+			return task.CreatedAt, task.ID
+		case "updated_at":
+			return task.UpdatedAt, task.ID
+		default:
+			return task.ID, task.ID
+		}
+	})
+	if len(tasks) > page.Limit {
+		tasks = tasks[:page.Limit]
+	}
+	if page.WithTotal {
+		info.Total = &total
+	}
+
+	return tasks, info, nil
+}
+
+// ReplayFailed возвращает в очередь failed-задачи, подходящие под фильтр: попытки
+// начинаются заново, а каждая повторная отправка записывается в webhook_task_replays
+func (r *WebhookTaskRepository) ReplayFailed(filter models.WebhookTaskFilter, actor models.TaskActor) (int64, error) {
+	filter.Status = "failed"
+	return r.replay(filter, actor)
+}
+
+// RetryTasks возвращает в очередь задачи, подходящие под фильтр: failed и discarded -
+// с новыми попытками, как ReplayFailed, а ожидающим pending назначает отправку на сейчас
+func (r *WebhookTaskRepository) RetryTasks(filter models.WebhookTaskFilter, actor models.TaskActor) (int64, error) {
+	replayFilter := filter
+	replayFilter.Statuses = []string{"failed", "discarded"}
+	replayed, err := r.replay(replayFilter, actor)
+	if err != nil {
+		return replayed, err
+	}
+
+	filter.Statuses = []string{"pending"}
+	result := applyTaskFilter(r.db.Model(&models.WebhookTask{}), filter).
+		Where("next_attempt > NOW()").
+		Updates(map[string]interface{}{
+			"next_attempt": gorm.Expr("NOW()"),
+			"updated_at":   gorm.Expr("NOW()"),
+		})
+	return replayed + result.RowsAffected, result.Error
+}
+
+// CancelTasks снимает с доставки задачи в pending и processing, подходящие под фильтр.
+// Отправка, которая уже идет, завершится, но ее результат будет отброшен.
+func (r *WebhookTaskRepository) CancelTasks(filter models.WebhookTaskFilter, actor models.TaskActor) (int64, error) {
+	reason := "cancelled"
+	if actor.Name != nil {
+		reason = "cancelled by " + *actor.Name
+	}
+	if actor.Note != nil {
+		reason += ": " + *actor.Note
+	}
+	filter.Statuses = []string{"pending", "processing"}
+	result := applyTaskFilter(r.db.Model(&models.WebhookTask{}), filter).
+		Updates(map[string]interface{}{
+			"status":       "discarded",
+			"last_error":   reason,
+			"locked_by":    nil,
+			"locked_until": nil,
+			"updated_at":   gorm.Expr("NOW()"),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteTasks удаляет задачи, подходящие под фильтр, кроме тех, что сейчас отправляются
+func (r *WebhookTaskRepository) DeleteTasks(filter models.WebhookTaskFilter) (int64, error) {
+	result := applyTaskFilter(r.db, filter).
+		Where("NOT (status = 'processing' AND locked_until >= NOW())").
+		Delete(&models.WebhookTask{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *WebhookTaskRepository) replay(filter models.WebhookTaskFilter, actor models.TaskActor) (int64, error) {
+	var replayed int64
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var tasks []models.WebhookTask
+		err := applyTaskFilter(tx.Model(&models.WebhookTask{}), filter).
+			Select("id", "attempts", "last_error").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Find(&tasks).Error
+		if err != nil || len(tasks) == 0 {
+			return err
+		}
+
+		ids := make([]uint, 0, len(tasks))
+		replays := make([]models.WebhookTaskReplay, 0, len(tasks))
+		now := time.Now()
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+			replays = append(replays, models.WebhookTaskReplay{
+				TaskID:           task.ID,
+				ReplayedBy:       actor.Name,
+				Note:             actor.Note,
+				PreviousAttempts: task.Attempts,
+				PreviousError:    task.LastError,
+				CreatedAt:        now,
+			})
+		}
+		if err := tx.CreateInBatches(&replays, 500).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.WebhookTask{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":       "pending",
+				"attempts":     0,
+				"next_attempt": now,
+				"last_error":   nil,
+				"updated_at":   now,
+			})
+		replayed = result.RowsAffected
+		return result.Error
+	})
+
+	return replayed, err
+}
+
+// DiscardFailed переводит failed-задачи, подходящие под фильтр, в статус discarded
+func (r *WebhookTaskRepository) DiscardFailed(filter models.WebhookTaskFilter) (int64, error) {
+	filter.Status = "failed"
+	result := applyTaskFilter(r.db.Model(&models.WebhookTask{}), filter).
+		Updates(map[string]interface{}{
+			"status":     "discarded",
+			"updated_at": gorm.Expr("NOW()"),
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *WebhookTaskRepository) GetReplays(taskID uint) ([]models.WebhookTaskReplay, error) {
+	var replays []models.WebhookTaskReplay
+	err := r.db.
+		Where("task_id = ?", taskID).
+		Order("created_at ASC, id ASC").
+		Find(&replays).Error
+	return replays, err
+}
+
+func applyTaskFilter(query *gorm.DB, filter models.WebhookTaskFilter) *gorm.DB {
+	if filter.ID != nil {
+		query = query.Where("id = ?", *filter.ID)
+	}
+	if len(filter.IDs) > 0 {
+		query = query.Where("id IN ?", filter.IDs)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.IncidentID != nil {
+		query = query.Where("incident_id = ?", *filter.IncidentID)
+	}
+	if filter.SubscriberID != nil {
+		query = query.Where("subscriber_id = ?", *filter.SubscriberID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.From != nil {
+		query = query.Where("updated_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("updated_at < ?", *filter.To)
+	}
+	return query
+}
+
 func (r *WebhookTaskRepository) GetIncidentByID(id uint) (*models.Incident, error) {
 	var incident models.Incident
 	if err := r.db.
+		Preload("Translations").
+		Preload("Attachments").
 		Where("id = ?", id).
 		First(&incident).Error; err != nil {
 		return nil, err
@@ -49,6 +532,71 @@ func (r *WebhookTaskRepository) GetIncidentByID(id uint) (*models.Incident, erro
 	return &incident, nil
 }
 
+// GetAlertedRecipients возвращает пользователей, которым уже доставлено оповещение
+// об инциденте (в том числе в составе сводки), с языком последнего оповещения
+func (r *WebhookTaskRepository) GetAlertedRecipients(incidentID uint) ([]models.AlertRecipient, error) {
+	var recipients []models.AlertRecipient
+	args := append(alertedArgs(incidentID), incidentID, models.EventUserNearIncident)
+	err := r.db.Raw(`
+		SELECT DISTINCT ON (user_id) user_id, language
+		FROM (
+			SELECT user_id, language, created_at
+			FROM webhook_tasks
+			WHERE `+alertedCondition+` AND status = 'completed'
+			UNION ALL
+			SELECT e.user_id, e.language, e.created_at
+			FROM webhook_digest_entries e
+			JOIN webhook_tasks t ON t.id = e.digest_task_id
+			WHERE e.incident_id = ? AND e.event = ? AND t.status = 'completed'
+		) alerted
+		ORDER BY user_id, created_at DESC`,
+		args...).
+		Scan(&recipients).Error
+	return recipients, err
+}
+
+// WasUserAlerted проверяет, ставилось ли пользователю оповещение об инциденте
+// (отдельной задачей или записью сводки)
+func (r *WebhookTaskRepository) WasUserAlerted(incidentID uint, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.WebhookTask{}).
+		Where(alertedCondition, alertedArgs(incidentID)...).
+		Where("user_id = ?", userID).
+		Limit(1).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	err = r.db.Model(&models.WebhookDigestEntry{}).
+		Where("incident_id = ? AND event = ? AND user_id = ?", incidentID, models.EventUserNearIncident, userID).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// alertedCondition отбирает задачи оповещения об инциденте: отдельные и объединенные,
+// в которых инцидент указан в payload.incident_ids
+const alertedCondition = `((incident_id = ? AND event = ?) OR (event = ? AND payload->'incident_ids' @> ?::jsonb))`
+
+func alertedArgs(incidentID uint) []interface{} {
+	return []interface{}{
+		incidentID, models.EventUserNearIncident,
+		models.EventUserNearIncidents, fmt.Sprintf("[%d]", incidentID),
+	}
+}
+
+// GetIncidentsByIDs возвращает инциденты с переводами и вложениями
+func (r *WebhookTaskRepository) GetIncidentsByIDs(ids []uint) ([]models.Incident, error) {
+	var incidents []models.Incident
+	err := r.db.
+		Preload("Translations").
+		Preload("Attachments").
+		Where("id IN ?", ids).
+		Find(&incidents).Error
+	return incidents, err
+}
+
 func (r *WebhookTaskRepository) GetTasksByStatus(status string, limit int) ([]models.WebhookTask, error) {
 	var tasks []models.WebhookTask
 	err := r.db.
@@ -85,36 +633,90 @@ func (r *WebhookTaskRepository) GetCompletedTasks(limit int) ([]models.WebhookTa
 
 func (r *WebhookTaskRepository) GetStats() (map[string]int64, error) {
 	var stats struct {
-		Pending   int64
-		Completed int64
-		Failed    int64
+		Pending    int64
+		Processing int64
+		Completed  int64
+		Failed     int64
+		Discarded  int64
 	}
 
 	err := r.db.
 		Table("webhook_tasks").
 		Select(`
-			SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) as pending,
-			SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END) as completed,
-			SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) as failed
+			COALESCE(SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END), 0) as pending,
+			COALESCE(SUM(CASE WHEN status = 'processing' THEN 1 ELSE 0 END), 0) as processing,
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END), 0) as completed,
+			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0) as failed,
+			COALESCE(SUM(CASE WHEN status = 'discarded' THEN 1 ELSE 0 END), 0) as discarded
 		`).
 		Row().
-		Scan(&stats.Pending, &stats.Completed, &stats.Failed)
+		Scan(&stats.Pending, &stats.Processing, &stats.Completed, &stats.Failed, &stats.Discarded)
 
 	if err != nil {
 		return nil, err
 	}
 
 	return map[string]int64{
-		"pending":   stats.Pending,
-		"completed": stats.Completed,
-		"failed":    stats.Failed,
+		"pending":    stats.Pending,
+		"processing": stats.Processing,
+		"completed":  stats.Completed,
+		"failed":     stats.Failed,
+		"discarded":  stats.Discarded,
 	}, nil
 }
 
 
-// CleanOldTasks удаляет старые задачи ( > 30 дней)
-func (r *WebhookTaskRepository) CleanOldTasks() error {
-	return r.db.
-		Where("created_at < NOW() - INTERVAL '30 days'").
-		Delete(&models.WebhookTask{}).Error
+// GetAgeStats возвращает число незавершенных и failed-задач по статусам и возрасту
+func (r *WebhookTaskRepository) GetAgeStats() (map[string]map[string]int64, error) {
+	bucket := "CASE"
+	var args []interface{}
+	for _, b := range models.TaskAgeBuckets {
+		bucket += " WHEN created_at > NOW() - make_interval(secs => ?) THEN ?"
+		args = append(args, b.Max.Seconds(), b.Name)
+	}
+	bucket += " ELSE ? END"
+	args = append(args, models.TaskAgeOlder)
+
+	var rows []struct {
+		Status string
+		Bucket string
+		Count  int64
+	}
+	err := r.db.
+		Table("webhook_tasks").
+		Select("status, "+bucket+" AS bucket, COUNT(*) AS count", args...).
+		Where("status IN ?", []string{"pending", "processing", "failed"}).
+		Group("status, bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]map[string]int64{}
+	for _, row := range rows {
+		if stats[row.Status] == nil {
+			stats[row.Status] = map[string]int64{}
+		}
+		stats[row.Status][row.Bucket] = row.Count
+	}
+	return stats, nil
+}
+
+// GetOldestPending возвращает готовую к отправке задачу, которая ждет дольше всех, или nil
+func (r *WebhookTaskRepository) GetOldestPending() (*models.OldestPendingTask, error) {
+	var oldest []models.OldestPendingTask
+	err := r.db.
+		Table("webhook_tasks").
+		// Задержка считается не раньше создания задачи: next_attempt мог остаться пустым
+		Select(`id AS task_id, subscriber_id, created_at, next_attempt,
+			EXTRACT(EPOCH FROM NOW() - GREATEST(next_attempt, created_at)) AS latency_seconds,
+			EXTRACT(EPOCH FROM NOW() - created_at) AS age_seconds`).
+		Where("status = 'pending' AND next_attempt <= NOW()").
+		Order("GREATEST(next_attempt, created_at) ASC, id ASC").
+		Limit(1).
+		Scan(&oldest).Error
+	if err != nil || len(oldest) == 0 {
+		return nil, err
+	}
+	return &oldest[0], nil
 }
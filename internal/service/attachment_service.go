@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+	"geowarns/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrAttachmentTooLarge    = errors.New("attachment is too large")
+	ErrAttachmentEmpty       = errors.New("attachment is empty")
+	ErrUnsupportedAttachment = errors.New("unsupported attachment type")
+)
+
+// allowedAttachmentTypes - разрешенные типы вложений (определяются по содержимому файла)
+// и расширения, с которыми они сохраняются
+var allowedAttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// типы, для которых можно построить миниатюру стандартной библиотекой
+var thumbnailTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type AttachmentService struct {
+	repo         *repository.AttachmentRepository
+	incidentRepo *repository.IncidentRepository
+	store        storage.BlobStore
+	maxSize      int64
+	logger       *zap.Logger
+}
+
+func NewAttachmentService(
+	repo *repository.AttachmentRepository,
+	incidentRepo *repository.IncidentRepository,
+	store storage.BlobStore,
+	maxSize int64,
+	logger *zap.Logger,
+) *AttachmentService {
+	return &AttachmentService{
+		repo:         repo,
+		incidentRepo: incidentRepo,
+		store:        store,
+		maxSize:      maxSize,
+		logger:       logger,
+	}
+}
+
+// Upload проверяет файл, сохраняет его (и миниатюру для изображений) в хранилище
+// и создает запись о вложении
+func (s *AttachmentService) Upload(ctx context.Context, incidentID uint, fileName string, body io.Reader) (*models.IncidentAttachment, error) {
+	if _, err := s.incidentRepo.GetByID(incidentID); err != nil {
+		return nil, err
+	}
+
+	data, contentType, err := s.readFile(body)
+	if err != nil {
+		return nil, err
+	}
+	ext := allowedAttachmentTypes[contentType]
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("incidents/%d/%s%s", incidentID, id, ext)
+
+	if err := s.store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	attachment := &models.IncidentAttachment{
+		IncidentID:  incidentID,
+		FileName:    sanitizeFileName(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  key,
+	}
+
+	if thumbnailTypes[contentType] {
+		thumbKey := fmt.Sprintf("incidents/%d/%s_thumb.jpg", incidentID, id)
+		if err := s.storeThumbnail(ctx, thumbKey, data); err != nil {
+			// Вложение без миниатюры все равно полезно
+			s.logger.Warn("Failed to create thumbnail", zap.String("key", key), zap.Error(err))
+		} else {
+			attachment.ThumbnailKey = &thumbKey
+		}
+	}
+
+	if err := s.repo.Create(attachment); err != nil {
+		s.deleteBlobs(ctx, attachment)
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// ImportBlob создает вложение инцидента из файла, уже лежащего в хранилище
+// (например, фото из пользовательского сообщения)
+func (s *AttachmentService) ImportBlob(ctx context.Context, incidentID uint, key, fileName string) (*models.IncidentAttachment, error) {
+	body, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return s.Upload(ctx, incidentID, fileName, body)
+}
+
+// StoreImage сохраняет изображение вне инцидента и возвращает его ключ и тип
+func (s *AttachmentService) StoreImage(ctx context.Context, prefix string, body io.Reader) (string, string, error) {
+	data, contentType, err := s.readFile(body)
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", "", fmt.Errorf("%w: %s", ErrUnsupportedAttachment, contentType)
+	}
+	ext := allowedAttachmentTypes[contentType]
+
+	id, err := randomID()
+	if err != nil {
+		return "", "", err
+	}
+	key := prefix + "/" + id + ext
+
+	if err := s.store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return "", "", fmt.Errorf("failed to store image: %w", err)
+	}
+	return key, contentType, nil
+}
+
+// OpenBlob читает файл из хранилища по ключу
+func (s *AttachmentService) OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.store.Get(ctx, key)
+}
+
+// DeleteBlob удаляет файл из хранилища, ошибка только логируется
+func (s *AttachmentService) DeleteBlob(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		s.logger.Warn("Failed to delete blob", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// readFile читает файл с учетом ограничения размера и определяет его тип по содержимому
+func (s *AttachmentService) readFile(body io.Reader) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(body, s.maxSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, "", fmt.Errorf("%w: limit is %d bytes", ErrAttachmentTooLarge, s.maxSize)
+	}
+	if len(data) == 0 {
+		return nil, "", ErrAttachmentEmpty
+	}
+
+	contentType := detectContentType(data)
+	if _, ok := allowedAttachmentTypes[contentType]; !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedAttachment, contentType)
+	}
+	return data, contentType, nil
+}
+
+func (s *AttachmentService) storeThumbnail(ctx context.Context, key string, data []byte) error {
+	thumb, err := makeThumbnail(data, thumbnailMaxSide)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(ctx, key, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg")
+}
+
+func (s *AttachmentService) GetAttachments(incidentID uint) ([]models.IncidentAttachment, error) {
+	if _, err := s.incidentRepo.GetByID(incidentID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByIncident(incidentID)
+}
+
+// OpenContent возвращает содержимое вложения или его миниатюры
+func (s *AttachmentService) OpenContent(ctx context.Context, incidentID, id uint, thumbnail bool) (*models.IncidentAttachment, io.ReadCloser, error) {
+	attachment, err := s.repo.GetByID(incidentID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := attachment.StorageKey
+	if thumbnail {
+		if attachment.ThumbnailKey == nil {
+			return nil, nil, storage.ErrNotFound
+		}
+		key = *attachment.ThumbnailKey
+	}
+
+	body, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, body, nil
+}
+
+func (s *AttachmentService) Delete(ctx context.Context, incidentID, id uint) error {
+	attachment, err := s.repo.GetByID(incidentID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(attachment.ID); err != nil {
+		return err
+	}
+	s.deleteBlobs(ctx, attachment)
+	return nil
+}
+
+// Discard удаляет только что загруженные вложения вместе с файлами, когда загрузка
+// нескольких файлов прервалась на одном из них
+func (s *AttachmentService) Discard(ctx context.Context, attachments []*models.IncidentAttachment) {
+	for _, attachment := range attachments {
+		if err := s.repo.Delete(attachment.ID); err != nil {
+			s.logger.Warn("Failed to discard attachment", zap.Uint("id", attachment.ID), zap.Error(err))
+			continue
+		}
+		s.deleteBlobs(ctx, attachment)
+	}
+}
+
+func (s *AttachmentService) deleteBlobs(ctx context.Context, attachment *models.IncidentAttachment) {
+	keys := []string{attachment.StorageKey}
+	if attachment.ThumbnailKey != nil {
+		keys = append(keys, *attachment.ThumbnailKey)
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			s.logger.Warn("Failed to delete attachment blob", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+func detectContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
+
+func randomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
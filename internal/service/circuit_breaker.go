@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// CircuitConfig задает автоматический выключатель получателей вебхуков. После
+// FailureThreshold неудачных отправок подряд доставка получателю приостанавливается
+// на OpenTimeout (пауза удваивается при каждом повторном размыкании, но не больше
+// MaxOpenTimeout), затем выполняется одна пробная отправка. Если неудачи длятся
+// дольше DisableAfter, получатель отключается и оператор получает уведомление.
+type CircuitConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	MaxOpenTimeout   time.Duration
+	DisableAfter     time.Duration
+}
+
+func DefaultCircuitConfig() CircuitConfig {
+	return CircuitConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      time.Minute,
+		MaxOpenTimeout:   30 * time.Minute,
+		DisableAfter:     24 * time.Hour,
+	}
+}
+
+// openFor - пауза после trips-го размыкания выключателя
+func (c CircuitConfig) openFor(trips int) time.Duration {
+	delay := c.OpenTimeout
+	for i := 1; i < trips && delay < c.MaxOpenTimeout; i++ {
+		delay *= 2
+	}
+	if delay > c.MaxOpenTimeout {
+		delay = c.MaxOpenTimeout
+	}
+	return delay
+}
+
+// circuitProbeTimeout - сколько пробная отправка удерживает half_open; если обработчик
+// упал, не завершив пробу, по истечении этого времени пробу выполнит другой
+const circuitProbeTimeout = 2 * WebhookRequestTimeout
+
+type CircuitBreaker struct {
+	repo           *repository.WebhookCircuitRepository
+	subscriberRepo *repository.WebhookSubscriberRepository
+	notifier       *OperatorNotifier
+	config         CircuitConfig
+	logger         *zap.Logger
+}
+
+func NewCircuitBreaker(
+	repo *repository.WebhookCircuitRepository,
+	subscriberRepo *repository.WebhookSubscriberRepository,
+	notifier *OperatorNotifier,
+	config CircuitConfig,
+	logger *zap.Logger,
+) *CircuitBreaker {
+	return &CircuitBreaker{
+		repo:           repo,
+		subscriberRepo: subscriberRepo,
+		notifier:       notifier,
+		config:         config,
+		logger:         logger,
+	}
+}
+
+// Allow сообщает, можно ли сейчас отправлять получателю. Если нельзя, возвращает
+// время, до которого задачу стоит отложить. При разомкнутом выключателе с истекшей
+// паузой разрешается только одна пробная отправка.
+func (b *CircuitBreaker) Allow(subscriberID uint) (bool, time.Time, error) {
+	circuit, err := b.repo.Get(subscriberID)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if circuit.State == models.CircuitClosed {
+		return true, time.Time{}, nil
+	}
+
+	now := time.Now()
+	if circuit.RetryAt != nil && circuit.RetryAt.After(now) {
+		return false, *circuit.RetryAt, nil
+	}
+
+	probeUntil := now.Add(circuitProbeTimeout)
+	ok, err := b.repo.TryProbe(subscriberID, probeUntil)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	return ok, probeUntil, nil
+}
+
+// Record учитывает результат отправки получателю. Ответ 4xx означает, что получатель
+// жив, поэтому считается успехом; ошибки, не связанные с получателем (например,
+// шаблон тела), на выключатель не влияют.
+func (b *CircuitBreaker) Record(subscriberID uint, deliveryErr error) {
+	if deliveryErr == nil {
+		b.recordSuccess(subscriberID)
+		return
+	}
+
+	var de *DeliveryError
+	if !errors.As(deliveryErr, &de) {
+		return
+	}
+	if !de.Retryable {
+		if de.StatusCode > 0 {
+			b.recordSuccess(subscriberID)
+		}
+		return
+	}
+
+	circuit, opened, disabled, err := b.repo.RecordFailure(subscriberID, repository.CircuitFailure{
+		Error:        deliveryErr.Error(),
+		Threshold:    b.config.FailureThreshold,
+		OpenFor:      b.config.openFor,
+		DisableAfter: b.config.DisableAfter,
+	})
+	if err != nil {
+		b.logger.Error("Failed to record webhook failure",
+			zap.Uint("subscriber_id", subscriberID),
+			zap.Error(err))
+		return
+	}
+
+	if opened {
+		b.logger.Warn("Webhook circuit opened, deliveries paused",
+			zap.Uint("subscriber_id", subscriberID),
+			zap.Int("consecutive_failures", circuit.ConsecutiveFailures),
+			zap.Timep("retry_at", circuit.RetryAt),
+			zap.Error(deliveryErr))
+	}
+	if disabled {
+		b.notifyDisabled(circuit)
+	}
+}
+
+func (b *CircuitBreaker) recordSuccess(subscriberID uint) {
+	if err := b.repo.RecordSuccess(subscriberID); err != nil {
+		b.logger.Error("Failed to record webhook success",
+			zap.Uint("subscriber_id", subscriberID),
+			zap.Error(err))
+	}
+}
+
+func (b *CircuitBreaker) notifyDisabled(circuit *models.WebhookCircuit) {
+	subscriber, err := b.subscriberRepo.GetByID(circuit.SubscriberID)
+	if err != nil {
+		b.logger.Error("Failed to load disabled webhook subscriber",
+			zap.Uint("subscriber_id", circuit.SubscriberID),
+			zap.Error(err))
+		return
+	}
+	b.notifier.SubscriberDisabled(subscriber, circuit)
+}
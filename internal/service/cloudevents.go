@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"geowarns/internal/models"
+	"geowarns/pkg/cloudevents"
+)
+
+const (
+	// cloudEventSource - атрибут source исходящих событий
+	cloudEventSource = "/geowarns/webhooks"
+	// cloudEventTypePrefix - префикс атрибута type: com.geowarns.incident.created
+	cloudEventTypePrefix = "com.geowarns."
+)
+
+// wrapEnvelope оборачивает тело вебхука в конверт получателя. Атрибуты события
+// зависят только от задачи, поэтому при повторах id и time не меняются и получатель
+// может отбрасывать дубликаты.
+func wrapEnvelope(task models.WebhookTask, envelope string, payload []byte) ([]byte, http.Header, error) {
+	mode := ""
+	switch envelope {
+	case models.EnvelopeCloudEventsStructured:
+		mode = cloudevents.ModeStructured
+	case models.EnvelopeCloudEventsBinary:
+		mode = cloudevents.ModeBinary
+	default:
+		header := make(http.Header)
+		header.Set("Content-Type", "application/json")
+		return payload, header, nil
+	}
+
+	event := task.Event
+	if event == "" {
+		event = models.EventUserNearIncident
+	}
+	// Сводка относится к нескольким инцидентам, поэтому без subject
+	subject := ""
+	if event != models.EventDigest {
+		subject = strconv.FormatUint(uint64(task.IncidentID), 10)
+	}
+
+	ce := cloudevents.New(
+		fmt.Sprintf("geowarns-task-%d", task.ID),
+		cloudEventSource,
+		cloudEventTypePrefix+event,
+		subject,
+		task.CreatedAt,
+		payload,
+	)
+	body, header, err := ce.Encode(mode)
+	if err != nil {
+		return nil, nil, permanentError("failed to encode CloudEvent: %v", err)
+	}
+	return body, header, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"geowarns/internal/geo"
+	"geowarns/internal/models"
+)
+
+// alertItem - инцидент в объединенном оповещении
+type alertItem struct {
+	*models.Incident
+	DistanceMeters float64 `json:"distance_meters"`
+}
+
+// alertSummary - самый опасный инцидент проверки, выводится в начале объединенного оповещения
+type alertSummary struct {
+	IncidentCount   int    `json:"incident_count"`
+	HighestSeverity string `json:"highest_severity"`
+	IncidentID      uint   `json:"incident_id"`
+	Title           string `json:"title"`
+	Category        string `json:"category"`
+}
+
+// buildAlertTasks строит задачи оповещения по новым для пользователя инцидентам.
+// Получателям в режиме digest достаются записи для сводки, по одной на инцидент.
+func buildAlertTasks(
+	req *models.LocationCheckRequest,
+	language string,
+	incidents []models.Incident,
+	subscribers []models.WebhookSubscriber,
+	matched map[uint][]*models.WebhookSubscriber,
+	fresh map[uint]bool,
+) models.AlertBatch {
+	var batch models.AlertBatch
+	combined := make(map[uint][]*models.Incident)
+
+	for i := range incidents {
+		incident := &incidents[i]
+		if !fresh[incident.ID] {
+			continue
+		}
+		for _, subscriber := range matched[incident.ID] {
+			if subscriber.DeliveryMode == models.DeliveryModeCombined {
+				combined[subscriber.ID] = append(combined[subscriber.ID], incident)
+				continue
+			}
+			task := models.WebhookTask{
+				IncidentID:  incident.ID,
+				UserID:      req.UserID,
+				Event:       models.EventUserNearIncident,
+				Status:      "pending",
+				Language:    language,
+				NextAttempt: time.Now(),
+			}
+			if subscriber.DeliveryMode == models.DeliveryModeDigest {
+				task.Payload = models.JSON{
+					"latitude":  req.Latitude,
+					"longitude": req.Longitude,
+				}
+			}
+			batch.Add(task, subscriber)
+		}
+	}
+
+	// Обход в порядке получателей, чтобы задачи создавались детерминированно
+	for i := range subscribers {
+		list := combined[subscribers[i].ID]
+		if len(list) == 0 {
+			continue
+		}
+		items := make([]alertItem, 0, len(list))
+		for _, incident := range list {
+			items = append(items, newAlertItem(incident, req.Latitude, req.Longitude))
+		}
+		sortAlertItems(items)
+
+		ids := make([]uint, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		batch.Add(models.WebhookTask{
+			// Задача привязана к самому опасному инциденту, остальные перечислены в payload
+			IncidentID:  ids[0],
+			UserID:      req.UserID,
+			Event:       models.EventUserNearIncidents,
+			Status:      "pending",
+			Language:    language,
+			NextAttempt: time.Now(),
+			Payload: models.JSON{
+				"incident_ids": ids,
+				"latitude":     req.Latitude,
+				"longitude":    req.Longitude,
+			},
+		}, &subscribers[i])
+	}
+
+	return batch
+}
+
+func newAlertItem(incident *models.Incident, lat, lng float64) alertItem {
+	return alertItem{
+		Incident:       incident,
+		DistanceMeters: geo.DistanceMeters(lat, lng, incident.Latitude, incident.Longitude),
+	}
+}
+
+// sortAlertItems упорядочивает инциденты: сначала более опасные, при равной опасности - ближайшие
+func sortAlertItems(items []alertItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		ri, rj := models.SeverityRank(items[i].Severity), models.SeverityRank(items[j].Severity)
+		if ri != rj {
+			return ri > rj
+		}
+		return items[i].DistanceMeters < items[j].DistanceMeters
+	})
+}
+
+// buildCombinedPayload собирает тело объединенного оповещения из задачи EventUserNearIncidents
+func (s *WebhookService) buildCombinedPayload(task models.WebhookTask) (map[string]interface{}, error) {
+	ids, err := payloadIDs(task.Payload["incident_ids"])
+	if err != nil {
+		return nil, permanentError("invalid combined alert payload: %v", err)
+	}
+
+	incidents, err := s.repo.GetIncidentsByIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incidents: %w", err)
+	}
+	if len(incidents) == 0 {
+		return nil, permanentError("incidents %v not found", ids)
+	}
+
+	return combinedPayload(task, incidents), nil
+}
+
+// combinedPayload собирает тело объединенного оповещения: сводку по самому опасному
+// инциденту и все инциденты по убыванию опасности
+func combinedPayload(task models.WebhookTask, incidents []models.Incident) map[string]interface{} {
+	lat, _ := task.Payload["latitude"].(float64)
+	lng, _ := task.Payload["longitude"].(float64)
+
+	items := make([]alertItem, 0, len(incidents))
+	for i := range incidents {
+		if task.Language != "" {
+			localizeIncident(&incidents[i], []string{task.Language})
+		}
+		items = append(items, newAlertItem(&incidents[i], lat, lng))
+	}
+	sortAlertItems(items)
+
+	top := items[0]
+	language := task.Language
+	if language == "" {
+		language = top.Language
+	}
+
+	return map[string]interface{}{
+		"event":    models.EventUserNearIncidents,
+		"user_id":  task.UserID,
+		"language": language,
+		"location": map[string]float64{
+			"latitude":  lat,
+			"longitude": lng,
+		},
+		"summary": alertSummary{
+			IncidentCount:   len(items),
+			HighestSeverity: top.Severity,
+			IncidentID:      top.ID,
+			Title:           top.Title,
+			Category:        top.Category,
+		},
+		"incidents": items,
+	}
+}
+
+// payloadIDs читает список ID из jsonb (числа приходят как float64)
+func payloadIDs(value interface{}) ([]uint, error) {
+	raw, ok := value.([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("incident_ids is missing")
+	}
+	ids := make([]uint, 0, len(raw))
+	for _, v := range raw {
+		n, ok := v.(float64)
+		if !ok || n <= 0 {
+			return nil, fmt.Errorf("invalid incident id %v", v)
+		}
+		ids = append(ids, uint(n))
+	}
+	return ids, nil
+}
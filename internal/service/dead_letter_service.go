@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+)
+
+// DeadLetterTask - задача из очереди недоставленных с телом вебхука и историей повторов
+type DeadLetterTask struct {
+	Task         *models.WebhookTask        `json:"task"`
+	Payload      json.RawMessage            `json:"payload,omitempty"`
+	PayloadError string                     `json:"payload_error,omitempty"`
+	Replays      []models.WebhookTaskReplay `json:"replays"`
+}
+
+// DeadLetterService - просмотр, повтор и удаление задач, доставка которых не удалась
+type DeadLetterService struct {
+	repo           *repository.WebhookTaskRepository
+	webhookService *WebhookService
+}
+
+func NewDeadLetterService(repo *repository.WebhookTaskRepository, webhookService *WebhookService) *DeadLetterService {
+	return &DeadLetterService{
+		repo:           repo,
+		webhookService: webhookService,
+	}
+}
+
+func (s *DeadLetterService) ListFailed(filter models.WebhookTaskFilter, page models.PageRequest) ([]models.WebhookTask, models.PageInfo, error) {
+	filter.Status = "failed"
+	filter.ID = nil
+	return s.repo.List(filter, page)
+}
+
+// GetTask возвращает задачу с телом, которое уйдет при повторной отправке.
+// Если тело построить нельзя (например, инцидент удален), ошибка попадает в PayloadError.
+func (s *DeadLetterService) GetTask(id uint) (*DeadLetterTask, error) {
+	task, err := s.repo.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	replays, err := s.repo.GetReplays(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeadLetterTask{Task: task, Replays: replays}
+	if result.Payload, err = s.webhookService.RenderPayload(*task); err != nil {
+		result.PayloadError = err.Error()
+	}
+	return result, nil
+}
+
+// Replay возвращает в очередь одну failed-задачу
+func (s *DeadLetterService) Replay(id uint, actor models.TaskActor) error {
+	if _, err := s.repo.GetTaskByID(id); err != nil {
+		return err
+	}
+	n, err := s.repo.ReplayFailed(models.WebhookTaskFilter{ID: &id}, actor)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrTaskNotFailed
+	}
+	return nil
+}
+
+// ReplayMatching возвращает в очередь все failed-задачи, подходящие под фильтр
+func (s *DeadLetterService) ReplayMatching(filter models.WebhookTaskFilter, actor models.TaskActor) (int64, error) {
+	filter.ID = nil
+	return s.repo.ReplayFailed(filter, actor)
+}
+
+// Discard снимает одну failed-задачу с доставки
+func (s *DeadLetterService) Discard(id uint) error {
+	if _, err := s.repo.GetTaskByID(id); err != nil {
+		return err
+	}
+	n, err := s.repo.DiscardFailed(models.WebhookTaskFilter{ID: &id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrTaskNotFailed
+	}
+	return nil
+}
+
+func (s *DeadLetterService) DiscardMatching(filter models.WebhookTaskFilter) (int64, error) {
+	filter.ID = nil
+	return s.repo.DiscardFailed(filter)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+)
+
+// DeliveryLogService - журнал попыток доставки вебхуков
+type DeliveryLogService struct {
+	attemptRepo    *repository.DeliveryAttemptRepository
+	taskRepo       *repository.WebhookTaskRepository
+	subscriberRepo *repository.WebhookSubscriberRepository
+}
+
+func NewDeliveryLogService(
+	attemptRepo *repository.DeliveryAttemptRepository,
+	taskRepo *repository.WebhookTaskRepository,
+	subscriberRepo *repository.WebhookSubscriberRepository,
+) *DeliveryLogService {
+	return &DeliveryLogService{
+		attemptRepo:    attemptRepo,
+		taskRepo:       taskRepo,
+		subscriberRepo: subscriberRepo,
+	}
+}
+
+func (s *DeliveryLogService) GetTaskAttempts(taskID uint) ([]models.WebhookDeliveryAttempt, error) {
+	if _, err := s.taskRepo.GetTaskByID(taskID); err != nil {
+		return nil, err
+	}
+	return s.attemptRepo.GetByTask(taskID)
+}
+
+func (s *DeliveryLogService) GetSubscriberAttempts(subscriberID uint, filter models.DeliveryAttemptFilter, page models.PageRequest) ([]models.WebhookDeliveryAttempt, models.PageInfo, error) {
+	if _, err := s.subscriberRepo.GetByID(subscriberID); err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	return s.attemptRepo.ListBySubscriber(subscriberID, filter, page)
+}
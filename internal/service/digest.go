@@ -0,0 +1,167 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"geowarns/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// digestIncident - инцидент в сводке со статистикой событий по нему
+type digestIncident struct {
+	*models.Incident
+	Events int `json:"events"`
+	Users  int `json:"users"`
+}
+
+// digestUser - пользователь, затронутый инцидентами сводки
+type digestUser struct {
+	UserID      string `json:"user_id"`
+	IncidentIDs []uint `json:"incident_ids"`
+}
+
+type digestCounts struct {
+	Events     int            `json:"events"`
+	Incidents  int            `json:"incidents"`
+	Users      int            `json:"users"`
+	ByEvent    map[string]int `json:"by_event"`
+	BySeverity map[string]int `json:"by_severity"`
+}
+
+// FlushDigests создает задачи для сводок, период которых завершился. Дальше сводки
+// отправляются обычной очередью задач, с повторами и журналом доставки.
+func (s *WebhookService) FlushDigests() error {
+	tasks, err := s.digestRepo.FlushDue(time.Now())
+	for _, task := range tasks {
+		s.logger.Info("Webhook digest queued",
+			zap.Uint("task_id", task.ID),
+			zap.Uintp("subscriber_id", task.SubscriberID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to flush digests: %w", err)
+	}
+	return nil
+}
+
+// buildDigestPayload собирает тело сводки из записей, привязанных к задаче EventDigest
+func (s *WebhookService) buildDigestPayload(task models.WebhookTask) (map[string]interface{}, error) {
+	entries, err := s.digestRepo.GetEntriesByTask(task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest entries: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, permanentError("digest %d has no entries", task.ID)
+	}
+
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.IncidentID)
+	}
+	incidents, err := s.repo.GetIncidentsByIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incidents: %w", err)
+	}
+	incidents = append(incidents, deletedIncidents(entries, incidents)...)
+
+	return digestPayload(task, entries, incidents), nil
+}
+
+// digestPayload собирает тело сводки: счетчики, инциденты с числом событий
+// и пользователей по каждому и затронутые пользователи
+func digestPayload(task models.WebhookTask, entries []models.WebhookDigestEntry, incidents []models.Incident) map[string]interface{} {
+	counts := digestCounts{
+		Events:     len(entries),
+		ByEvent:    make(map[string]int),
+		BySeverity: make(map[string]int),
+	}
+
+	perIncident := make(map[uint]*digestIncident)
+	incidentUsers := make(map[uint]map[string]bool)
+	userIncidents := make(map[string][]uint)
+	for _, entry := range entries {
+		counts.ByEvent[entry.Event]++
+		if perIncident[entry.IncidentID] == nil {
+			perIncident[entry.IncidentID] = &digestIncident{}
+			incidentUsers[entry.IncidentID] = make(map[string]bool)
+		}
+		perIncident[entry.IncidentID].Events++
+
+		if entry.UserID == "" || incidentUsers[entry.IncidentID][entry.UserID] {
+			continue
+		}
+		incidentUsers[entry.IncidentID][entry.UserID] = true
+		userIncidents[entry.UserID] = append(userIncidents[entry.UserID], entry.IncidentID)
+	}
+
+	items := make([]digestIncident, 0, len(incidents))
+	for i := range incidents {
+		item, ok := perIncident[incidents[i].ID]
+		if !ok {
+			continue
+		}
+		item.Incident = &incidents[i]
+		item.Users = len(incidentUsers[incidents[i].ID])
+		counts.BySeverity[incidents[i].Severity]++
+		items = append(items, *item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		ri, rj := models.SeverityRank(items[i].Severity), models.SeverityRank(items[j].Severity)
+		if ri != rj {
+			return ri > rj
+		}
+		return items[i].Events > items[j].Events
+	})
+
+	users := make([]digestUser, 0, len(userIncidents))
+	for userID, incidentIDs := range userIncidents {
+		users = append(users, digestUser{UserID: userID, IncidentIDs: incidentIDs})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+
+	counts.Incidents = len(items)
+	counts.Users = len(users)
+
+	return map[string]interface{}{
+		"event":         models.EventDigest,
+		"subscriber_id": task.SubscriberID,
+		"interval":      task.Payload["interval"],
+		"period": map[string]interface{}{
+			"start": task.Payload["period_start"],
+			"end":   task.Payload["period_end"],
+		},
+		"counts":    counts,
+		"incidents": items,
+		"users":     users,
+	}
+}
+
+// deletedIncidents восстанавливает удаленные инциденты сводки из копий в записях incident.deleted
+func deletedIncidents(entries []models.WebhookDigestEntry, found []models.Incident) []models.Incident {
+	seen := make(map[uint]bool, len(found))
+	for _, incident := range found {
+		seen[incident.ID] = true
+	}
+
+	var deleted []models.Incident
+	for _, entry := range entries {
+		snapshot, ok := entry.Payload["incident"]
+		if !ok || seen[entry.IncidentID] {
+			continue
+		}
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			continue
+		}
+		var incident models.Incident
+		if err := json.Unmarshal(data, &incident); err != nil {
+			continue
+		}
+		seen[entry.IncidentID] = true
+		deleted = append(deleted, incident)
+	}
+	return deleted
+}
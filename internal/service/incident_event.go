@@ -0,0 +1,78 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+)
+
+// IncidentEvents возвращает построитель задач о событии инцидента для всех подходящих
+// получателей. Для incident.deleted в payload сохраняется копия инцидента: к моменту
+// отправки его уже нет в базе.
+func (s *WebhookSubscriberService) IncidentEvents(event string, payload models.JSON) repository.IncidentEventsFunc {
+	return func(incident *models.Incident) (*models.AlertBatch, error) {
+		task := models.WebhookTask{
+			IncidentID:  incident.ID,
+			Event:       event,
+			Status:      "pending",
+			NextAttempt: time.Now(),
+			Payload:     models.JSON{},
+		}
+		for k, v := range payload {
+			task.Payload[k] = v
+		}
+		if event == models.EventIncidentDeleted {
+			task.Payload["incident"] = incident
+		}
+		return s.FanOut(incident, []models.WebhookTask{task})
+	}
+}
+
+// incidentChanges возвращает измененные поля инцидента в виде {"поле": {"old": .., "new": ..}}
+func incidentChanges(before, after *models.Incident, translations []models.IncidentTranslation) models.JSON {
+	changes := models.JSON{}
+	add := func(field string, old, new interface{}) {
+		if old != new {
+			changes[field] = models.JSON{"old": old, "new": new}
+		}
+	}
+
+	add("title", before.Title, after.Title)
+	add("description", derefString(before.Description), derefString(after.Description))
+	add("latitude", before.Latitude, after.Latitude)
+	add("longitude", before.Longitude, after.Longitude)
+	add("radius", before.Radius, after.Radius)
+	add("category", before.Category, after.Category)
+	add("severity", before.Severity, after.Severity)
+	add("is_active", before.IsActive, after.IsActive)
+	add("needs_review", before.NeedsReview, after.NeedsReview)
+	add("language", before.Language, after.Language)
+
+	if len(translations) > 0 {
+		languages := make([]string, 0, len(translations))
+		for _, t := range translations {
+			languages = append(languages, t.Language)
+		}
+		sort.Strings(languages)
+		changes["translations"] = languages
+	}
+	return changes
+}
+
+// incidentUpdateEvent выбирает событие по изменениям: отключение активного инцидента -
+// incident.resolved, остальные изменения - incident.updated
+func incidentUpdateEvent(before, after *models.Incident) string {
+	if before.IsActive && !after.IsActive {
+		return models.EventIncidentResolved
+	}
+	return models.EventIncidentUpdated
+}
+
+func derefString(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+)
+
+type IncidentService struct {
+	incidentRepo      *repository.IncidentRepository
+	subscriberService *WebhookSubscriberService
+	attachmentService *AttachmentService
+}
+
+func NewIncidentService(incidentRepo *repository.IncidentRepository, subscriberService *WebhookSubscriberService, attachmentService *AttachmentService) *IncidentService {
+	return &IncidentService{
+		incidentRepo:      incidentRepo,
+		subscriberService: subscriberService,
+		attachmentService: attachmentService,
+	}
+}
+
+// CreateIncident сохраняет инцидент вместе с переводами и ставит вебхуки incident.created
+func (s *IncidentService) CreateIncident(incident *models.Incident, translations map[string]models.IncidentTranslationInput) error {
+	lang, err := normalizeIncidentLanguage(incident.Language)
+	if err != nil {
+		return err
+	}
+	incident.Language = lang
+
+	if incident.Category, err = normalizeIncidentCategory(incident.Category); err != nil {
+		return err
+	}
+	if incident.Severity, err = normalizeIncidentSeverity(incident.Severity); err != nil {
+		return err
+	}
+
+	incident.Translations, err = buildTranslations(incident, translations)
+	if err != nil {
+		return err
+	}
+
+	return s.incidentRepo.Create(incident, s.subscriberService.IncidentEvents(models.EventIncidentCreated, nil))
+}
+
+// GetIncident возвращает инцидент, локализованный по списку предпочитаемых языков.
+// Для nil preferred возвращается исходный текст.
+func (s *IncidentService) GetIncident(id uint, preferred []string) (*models.Incident, error) {
+	incident, err := s.incidentRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	localizeIncident(incident, preferred)
+	return incident, nil
+}
+
+// UpdateIncident сохраняет изменения инцидента, добавляет/обновляет переводы и ставит
+// вебхуки incident.updated (или incident.resolved) со списком измененных полей
+func (s *IncidentService) UpdateIncident(incident *models.Incident, translations map[string]models.IncidentTranslationInput) error {
+	lang, err := normalizeIncidentLanguage(incident.Language)
+	if err != nil {
+		return err
+	}
+	incident.Language = lang
+
+	if incident.Category, err = normalizeIncidentCategory(incident.Category); err != nil {
+		return err
+	}
+	if incident.Severity, err = normalizeIncidentSeverity(incident.Severity); err != nil {
+		return err
+	}
+
+	updated, err := buildTranslations(incident, translations)
+	if err != nil {
+		return err
+	}
+
+	before, err := s.incidentRepo.GetByID(incident.ID)
+	if err != nil {
+		return err
+	}
+
+	var events repository.IncidentEventsFunc
+	if changes := incidentChanges(before, incident, updated); len(changes) > 0 {
+		events = s.subscriberService.IncidentEvents(incidentUpdateEvent(before, incident), models.JSON{"changes": changes})
+	}
+
+	if err := s.incidentRepo.Update(incident, updated, events); err != nil {
+		return err
+	}
+
+	fresh, err := s.incidentRepo.GetByID(incident.ID)
+	if err != nil {
+		return err
+	}
+	incident.Translations = fresh.Translations
+	return nil
+}
+
+// DeleteIncident удаляет инцидент, ставит вебхуки incident.deleted и удаляет
+// из хранилища файлы его вложений
+func (s *IncidentService) DeleteIncident(ctx context.Context, id uint) error {
+	attachments, err := s.incidentRepo.Delete(id, s.subscriberService.IncidentEvents(models.EventIncidentDeleted, nil))
+	if err != nil {
+		return err
+	}
+	for i := range attachments {
+		s.attachmentService.deleteBlobs(ctx, &attachments[i])
+	}
+	return nil
+}
+
+func (s *IncidentService) ListIncidents(filter models.IncidentFilter, page models.PageRequest, preferred []string) ([]models.Incident, models.PageInfo, error) {
+	incidents, info, err := s.incidentRepo.List(filter, page)
+	if err != nil {
+		return nil, info, err
+	}
+	localizeIncidents(incidents, preferred)
+	return incidents, info, nil
+}
+
+func (s *IncidentService) SearchIncidents(req models.IncidentSearchRequest, preferred []string) ([]models.IncidentSearchResult, int64, error) {
+	if req.Limit <= 0 {
+		req.Limit = models.DefaultPageLimit
+	}
+	if req.Limit > models.MaxPageLimit {
+		req.Limit = models.MaxPageLimit
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	results, total, err := s.incidentRepo.Search(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range results {
+		localizeIncident(&results[i].Incident, preferred)
+	}
+	return results, total, nil
+}
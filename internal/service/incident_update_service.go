@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+)
+
+var ErrEmptyUpdateMessage = errors.New("update message is required")
+
+type IncidentUpdateService struct {
+	updateRepo        *repository.IncidentUpdateRepository
+	incidentRepo      *repository.IncidentRepository
+	webhookTaskRepo   *repository.WebhookTaskRepository
+	subscriberService *WebhookSubscriberService
+}
+
+func NewIncidentUpdateService(
+	updateRepo *repository.IncidentUpdateRepository,
+	incidentRepo *repository.IncidentRepository,
+	webhookTaskRepo *repository.WebhookTaskRepository,
+	subscriberService *WebhookSubscriberService,
+) *IncidentUpdateService {
+	return &IncidentUpdateService{
+		updateRepo:        updateRepo,
+		incidentRepo:      incidentRepo,
+		webhookTaskRepo:   webhookTaskRepo,
+		subscriberService: subscriberService,
+	}
+}
+
+// PublishUpdate добавляет запись в хронологию инцидента. Если у записи выставлен
+// флаг notify, каждому уже оповещенному пользователю ставится задача вебхука.
+func (s *IncidentUpdateService) PublishUpdate(incidentID uint, req *models.IncidentUpdateCreateRequest) (*models.IncidentUpdate, error) {
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		return nil, ErrEmptyUpdateMessage
+	}
+
+	incident, err := s.incidentRepo.GetByID(incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &models.IncidentUpdate{
+		IncidentID: incidentID,
+		Message:    message,
+		Author:     req.Author,
+		Notify:     req.Notify,
+		CreatedAt:  time.Now(),
+	}
+
+	var batch *models.AlertBatch
+	if req.Notify {
+		var tasks []models.WebhookTask
+		recipients, err := s.webhookTaskRepo.GetAlertedRecipients(incidentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, recipient := range recipients {
+			tasks = append(tasks, models.WebhookTask{
+				IncidentID:  incidentID,
+				UserID:      recipient.UserID,
+				Event:       models.EventIncidentUpdate,
+				Status:      "pending",
+				Language:    recipient.Language,
+				NextAttempt: time.Now(),
+			})
+		}
+		update.NotifiedUsers = len(recipients)
+
+		batch, err = s.subscriberService.FanOut(incident, tasks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.updateRepo.Create(update, batch); err != nil {
+		return nil, err
+	}
+
+	return update, nil
+}
+
+func (s *IncidentUpdateService) GetUpdates(incidentID uint) ([]models.IncidentUpdate, error) {
+	if _, err := s.incidentRepo.GetByID(incidentID); err != nil {
+		return nil, err
+	}
+	return s.updateRepo.GetByIncident(incidentID)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"geowarns/internal/locale"
+	"geowarns/internal/models"
+)
+
+var (
+	ErrInvalidLanguage = errors.New("invalid language")
+	ErrInvalidCategory = errors.New("invalid category")
+	ErrInvalidSeverity = errors.New("invalid severity")
+)
+
+// localizeIncident подменяет заголовок и описание переводом на наиболее подходящий
+// из preferred язык. Если подходящего перевода нет, остается исходный текст.
+func localizeIncident(incident *models.Incident, preferred []string) {
+	if len(preferred) == 0 {
+		return
+	}
+
+	available := []string{incident.Language}
+	for _, t := range incident.Translations {
+		available = append(available, t.Language)
+	}
+
+	lang := locale.Resolve(preferred, available)
+	if lang == "" || lang == incident.Language {
+		return
+	}
+
+	for _, t := range incident.Translations {
+		if t.Language != lang {
+			continue
+		}
+		incident.Title = t.Title
+		if t.Description != nil {
+			incident.Description = t.Description
+		}
+		incident.Language = lang
+		return
+	}
+}
+
+func localizeIncidents(incidents []models.Incident, preferred []string) {
+	for i := range incidents {
+		localizeIncident(&incidents[i], preferred)
+	}
+}
+
+// buildTranslations проверяет переводы из запроса и превращает их в модели
+func buildTranslations(incident *models.Incident, input map[string]models.IncidentTranslationInput) ([]models.IncidentTranslation, error) {
+	translations := make([]models.IncidentTranslation, 0, len(input))
+	for tag, tr := range input {
+		lang := locale.Normalize(tag)
+		if !locale.IsSupported(lang) {
+			return nil, fmt.Errorf("%w: unsupported translation language %q", ErrInvalidLanguage, tag)
+		}
+		if lang == incident.Language {
+			return nil, fmt.Errorf("%w: translation language %q matches incident language", ErrInvalidLanguage, tag)
+		}
+		if strings.TrimSpace(tr.Title) == "" {
+			return nil, fmt.Errorf("%w: translation %q has empty title", ErrInvalidLanguage, tag)
+		}
+		translations = append(translations, models.IncidentTranslation{
+			IncidentID:  incident.ID,
+			Language:    lang,
+			Title:       tr.Title,
+			Description: tr.Description,
+		})
+	}
+	return translations, nil
+}
+
+func normalizeIncidentLanguage(lang string) (string, error) {
+	if lang == "" {
+		return locale.Default, nil
+	}
+	normalized := locale.Normalize(lang)
+	if !locale.IsSupported(normalized) {
+		return "", fmt.Errorf("%w: unsupported language %q", ErrInvalidLanguage, lang)
+	}
+	return normalized, nil
+}
+
+func normalizeIncidentCategory(category string) (string, error) {
+	if category == "" {
+		return models.DefaultIncidentCategory, nil
+	}
+	if !models.IsValidCategory(category) {
+		return "", fmt.Errorf("%w: unknown category %q", ErrInvalidCategory, category)
+	}
+	return category, nil
+}
+
+func normalizeIncidentSeverity(severity string) (string, error) {
+	if severity == "" {
+		return models.DefaultIncidentSeverity, nil
+	}
+	if !models.IsValidSeverity(severity) {
+		return "", fmt.Errorf("%w: unknown severity %q", ErrInvalidSeverity, severity)
+	}
+	return severity, nil
+}
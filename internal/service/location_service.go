@@ -1,6 +1,12 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"geowarns/internal/locale"
 	"geowarns/internal/models"
 	repository "geowarns/internal/repository"
 )
@@ -9,17 +15,25 @@ type LocationService struct {
 	locationCheckRepo *repository.LocationCheckRepository
 	incidentRepo      *repository.IncidentRepository
 	webhookTaskRepo   *repository.WebhookTaskRepository
+	subscriberService *WebhookSubscriberService
+	// cooldown - минимальный интервал между оповещениями пользователя об одном
+	// и том же неизменившемся инциденте, 0 отключает дедупликацию
+	cooldown time.Duration
 }
 
 func NewLocationService(
 	locationCheckRepo *repository.LocationCheckRepository,
 	incidentRepo *repository.IncidentRepository,
 	webhookTaskRepo *repository.WebhookTaskRepository,
+	subscriberService *WebhookSubscriberService,
+	cooldown time.Duration,
 ) *LocationService {
 	return &LocationService{
 		locationCheckRepo: locationCheckRepo,
 		incidentRepo:      incidentRepo,
 		webhookTaskRepo:   webhookTaskRepo,
+		subscriberService: subscriberService,
+		cooldown:          cooldown,
 	}
 }
 
@@ -40,28 +54,81 @@ func (s *LocationService) CheckLocation(req *models.LocationCheckRequest) (*mode
 		return nil, nil, err
 	}
 
-	for _, incident := range nearbyIncidents {
-		task := &models.WebhookTask{
-			IncidentID: incident.ID,
-			UserID:     req.UserID,
-			Status:     "pending",
+	// Язык получателя сохраняется в задаче, чтобы вебхук ушел с переведенным текстом
+	language := locale.Normalize(req.Language)
+	if !locale.IsSupported(language) {
+		language = ""
+	}
+
+	if err := s.enqueueAlerts(req, language, nearbyIncidents); err != nil {
+		return nil, nil, err
+	}
+
+	if language != "" {
+		localizeIncidents(nearbyIncidents, []string{language})
+	}
+
+	return check, nearbyIncidents, nil
+}
+
+// enqueueAlerts ставит задачи оповещения по найденным инцидентам: получателям в режиме
+// single - по задаче на инцидент, в режиме combined - одну задачу на всю проверку,
+// в режиме digest - записи для ближайшей сводки.
+// Инциденты, о которых пользователь недавно уже оповещен, пропускаются (см. cooldown).
+func (s *LocationService) enqueueAlerts(req *models.LocationCheckRequest, language string, incidents []models.Incident) error {
+	if len(incidents) == 0 {
+		return nil
+	}
+
+	subscribers, err := s.subscriberService.GetEnabled()
+	if err != nil {
+		return err
+	}
+
+	matched := make(map[uint][]*models.WebhookSubscriber)
+	fingerprints := make(map[uint]string)
+	for i := range incidents {
+		for j := range subscribers {
+			if ok, _ := subscribers[j].Match(models.EventUserNearIncident, &incidents[i]); ok {
+				matched[incidents[i].ID] = append(matched[incidents[i].ID], &subscribers[j])
+			}
 		}
-		if err := s.webhookTaskRepo.Create(task); err != nil {
-			continue
+		if len(matched[incidents[i].ID]) > 0 {
+			fingerprints[incidents[i].ID] = incidentFingerprint(&incidents[i])
 		}
 	}
+	if len(fingerprints) == 0 {
+		return nil
+	}
 
-	return check, nearbyIncidents, nil
+	_, err = s.webhookTaskRepo.CreateAlertTasks(req.UserID, fingerprints, s.cooldown, func(fresh map[uint]bool) models.AlertBatch {
+		return buildAlertTasks(req, language, incidents, subscribers, matched, fresh)
+	})
+	return err
 }
 
 func (s *LocationService) findNearbyIncidents(lat, lng float64) ([]models.Incident, error) {
 	return s.incidentRepo.GetActiveIncidents()
 }
 
-func (s *LocationService) GetLocationChecks() ([]models.LocationCheck, error) {
-	return s.locationCheckRepo.GetAll()
+func (s *LocationService) GetLocationChecks(filter models.LocationCheckFilter, page models.PageRequest) ([]models.LocationCheck, models.PageInfo, error) {
+	return s.locationCheckRepo.List(filter, page)
 }
 
 func (s *LocationService) GetLocationCheckByID(id uint) (*models.LocationCheck, error) {
 	return s.locationCheckRepo.GetByID(id)
 }
+
+// incidentFingerprint - хэш полей инцидента, изменение которых стоит повторного
+// оповещения даже до истечения cooldown. Голоса, переводы и вложения не учитываются.
+func incidentFingerprint(incident *models.Incident) string {
+	description := ""
+	if incident.Description != nil {
+		description = *incident.Description
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%t\x00%.6f\x00%.6f\x00%.1f",
+		incident.Title, description, incident.Category, incident.Severity,
+		incident.IsActive, incident.Latitude, incident.Longitude, incident.Radius)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"geowarns/internal/models"
+	"geowarns/pkg/webhooksig"
+
+	"go.uber.org/zap"
+)
+
+const (
+	EventSubscriberDisabled = "subscriber.disabled"
+
+	operatorRequestTimeout = 10 * time.Second
+)
+
+// OperatorNotifier сообщает оператору о проблемах с получателями вебхуков: пишет
+// в лог и, если задан адрес, отправляет подписанное уведомление на OPERATOR_WEBHOOK_URL
+type OperatorNotifier struct {
+	url        string
+	secrets    []string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+func NewOperatorNotifier(url string, secrets []string, logger *zap.Logger) *OperatorNotifier {
+	return &OperatorNotifier{
+		url:        url,
+		secrets:    secrets,
+		httpClient: &http.Client{Timeout: operatorRequestTimeout},
+		logger:     logger,
+	}
+}
+
+// SubscriberDisabled уведомляет об автоматическом отключении получателя
+func (n *OperatorNotifier) SubscriberDisabled(subscriber *models.WebhookSubscriber, circuit *models.WebhookCircuit) {
+	n.logger.Error("Webhook subscriber disabled after prolonged failures",
+		zap.Uint("subscriber_id", subscriber.ID),
+		zap.String("url", subscriber.URL),
+		zap.Stringp("contact", subscriber.Contact),
+		zap.Timep("failing_since", circuit.FailingSince),
+		zap.Int("consecutive_failures", circuit.ConsecutiveFailures),
+		zap.Stringp("last_error", circuit.LastError))
+
+	if n.url == "" {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"event":                EventSubscriberDisabled,
+		"subscriber_id":        subscriber.ID,
+		"description":          subscriber.Description,
+		"url":                  subscriber.URL,
+		"contact":              subscriber.Contact,
+		"failing_since":        circuit.FailingSince,
+		"disabled_at":          circuit.DisabledAt,
+		"consecutive_failures": circuit.ConsecutiveFailures,
+		"last_error":           circuit.LastError,
+	}
+	if err := n.send(payload); err != nil {
+		n.logger.Error("Failed to notify operator",
+			zap.Uint("subscriber_id", subscriber.ID),
+			zap.Error(err))
+	}
+}
+
+func (n *OperatorNotifier) send(payload map[string]interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secrets) > 0 {
+		webhooksig.SignRequest(req, n.secrets, body, time.Now())
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("operator webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
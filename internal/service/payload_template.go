@@ -0,0 +1,239 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"geowarns/internal/models"
+	"geowarns/internal/webhooktmpl"
+)
+
+var ErrInvalidPayloadPreview = errors.New("invalid payload preview")
+
+// PayloadPreview - тело вебхука, отрисованное по шаблону
+type PayloadPreview struct {
+	Event    string          `json:"event"`
+	Source   string          `json:"source"`
+	Template bool            `json:"template"`
+	Default  json.RawMessage `json:"default"`
+	Rendered json.RawMessage `json:"rendered"`
+}
+
+// applyTemplate переводит стандартное тело вебхука в формат получателя.
+// Ошибка шаблона не исправится повтором, поэтому она постоянная.
+func applyTemplate(template *string, payload []byte) ([]byte, error) {
+	if template == nil {
+		return payload, nil
+	}
+	rendered, err := webhooktmpl.Render(*template, payload)
+	if err != nil {
+		return nil, permanentError("failed to render payload template: %v", err)
+	}
+	return rendered, nil
+}
+
+// PreviewPayload отрисовывает тело вебхука по шаблону из запроса или шаблону получателя:
+// для реальной задачи (task_id) или для образца события (event)
+func (s *WebhookService) PreviewPayload(req *models.PayloadPreviewRequest) (*PayloadPreview, error) {
+	var subscriber *models.WebhookSubscriber
+	if req.SubscriberID != nil {
+		var err error
+		if subscriber, err = s.subscriberRepo.GetByID(*req.SubscriberID); err != nil {
+			return nil, err
+		}
+	}
+
+	template := req.Template
+	if template == nil && subscriber != nil {
+		template = subscriber.PayloadTemplate
+	}
+	if template != nil && strings.TrimSpace(*template) == "" {
+		template = nil
+	}
+
+	preview := &PayloadPreview{Template: template != nil}
+	var payload []byte
+	var err error
+
+	if req.TaskID != nil {
+		task, err := s.repo.GetTaskByID(*req.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = s.buildPayload(*task); err != nil {
+			return nil, err
+		}
+		preview.Event = task.Event
+		preview.Source = "task"
+	} else {
+		event := strings.TrimSpace(req.Event)
+		if event == "" {
+			event = models.EventUserNearIncident
+			if subscriber != nil {
+				event = deliveredEvents(subscriber)[0]
+			}
+		}
+		if payload, err = samplePayload(event); err != nil {
+			return nil, err
+		}
+		preview.Event = event
+		preview.Source = "sample"
+	}
+
+	preview.Default = payload
+	preview.Rendered = payload
+	if template != nil {
+		rendered, err := webhooktmpl.Render(*template, payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPayloadPreview, err)
+		}
+		preview.Rendered = rendered
+	}
+	return preview, nil
+}
+
+// validatePayloadTemplate проверяет, что шаблон дает корректный JSON для образцов
+// всех событий, которые получатель может получить
+func validatePayloadTemplate(subscriber *models.WebhookSubscriber) error {
+	if subscriber.PayloadTemplate == nil {
+		return nil
+	}
+	tmpl, err := webhooktmpl.Parse(*subscriber.PayloadTemplate)
+	if err != nil {
+		return fmt.Errorf("%w: payload_template: %v", ErrInvalidSubscriber, err)
+	}
+	for _, event := range deliveredEvents(subscriber) {
+		payload, err := samplePayload(event)
+		if err != nil {
+			return err
+		}
+		if _, err := tmpl.Render(payload); err != nil {
+			return fmt.Errorf("%w: payload_template for %s: %v", ErrInvalidSubscriber, event, err)
+		}
+	}
+	return nil
+}
+
+// deliveredEvents возвращает события, которые фактически приходят получателю
+// с учетом подписки и режима доставки
+func deliveredEvents(subscriber *models.WebhookSubscriber) []string {
+	if subscriber.DeliveryMode == models.DeliveryModeDigest {
+		return []string{models.EventDigest}
+	}
+
+	var events []string
+	for _, event := range models.WebhookEvents {
+		if !subscriber.Accepts(event) {
+			continue
+		}
+		if event == models.EventUserNearIncident && subscriber.DeliveryMode == models.DeliveryModeCombined {
+			event = models.EventUserNearIncidents
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		events = append(events, models.EventUserNearIncident)
+	}
+	return events
+}
+
+// samplePayload возвращает стандартное тело вебхука для события на вымышленных данных
+func samplePayload(event string) ([]byte, error) {
+	first := sampleIncident(1, "Пожар на складе", "fire", models.SeverityHigh, 43.2389, 76.8897)
+	second := sampleIncident(2, "Перекрыта дорога", "road_closure", models.SeverityMedium, 43.2410, 76.8920)
+
+	task := models.WebhookTask{
+		ID:         1,
+		IncidentID: first.ID,
+		UserID:     "user-123",
+		Event:      event,
+		Payload:    models.JSON{},
+	}
+
+	var payload map[string]interface{}
+	var err error
+	switch event {
+	case models.EventUserNearIncident, models.EventIncidentCreated:
+		payload = incidentPayload(task, &first)
+	case models.EventIncidentUpdate:
+		task.Payload["update"] = models.JSON{
+			"id":         1,
+			"message":    "Пожар локализован",
+			"author":     "operator",
+			"created_at": first.UpdatedAt,
+		}
+		payload = incidentPayload(task, &first)
+	case models.EventIncidentUpdated, models.EventIncidentResolved:
+		task.Payload["changes"] = models.JSON{
+			"severity": models.JSON{"old": models.SeverityMedium, "new": models.SeverityHigh},
+		}
+		if event == models.EventIncidentResolved {
+			first.IsActive = false
+			task.Payload["changes"] = models.JSON{
+				"is_active": models.JSON{"old": true, "new": false},
+			}
+		}
+		payload = incidentPayload(task, &first)
+	case models.EventIncidentDeleted:
+		if task.Payload["incident"], err = toJSONMap(first); err != nil {
+			return nil, err
+		}
+		if payload, err = deletedIncidentPayload(task); err != nil {
+			return nil, err
+		}
+	case models.EventUserNearIncidents:
+		task.Payload["latitude"] = 43.2395
+		task.Payload["longitude"] = 76.8905
+		payload = combinedPayload(task, []models.Incident{first, second})
+	case models.EventDigest:
+		subscriberID := uint(1)
+		end := time.Now().UTC().Truncate(time.Hour)
+		task.SubscriberID = &subscriberID
+		task.Payload["interval"] = models.DigestHourly
+		task.Payload["period_start"] = end.Add(-time.Hour)
+		task.Payload["period_end"] = end
+		entries := []models.WebhookDigestEntry{
+			{IncidentID: first.ID, Event: models.EventUserNearIncident, UserID: "user-123"},
+			{IncidentID: first.ID, Event: models.EventUserNearIncident, UserID: "user-456"},
+			{IncidentID: second.ID, Event: models.EventUserNearIncident, UserID: "user-123"},
+		}
+		payload = digestPayload(task, entries, []models.Incident{first, second})
+	default:
+		return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidPayloadPreview, event)
+	}
+
+	payload["timestamp"] = time.Now().Format(time.RFC3339)
+	return marshalPayload(payload)
+}
+
+func sampleIncident(id uint, title, category, severity string, lat, lng float64) models.Incident {
+	now := time.Now().UTC().Truncate(time.Second)
+	description := "Пример инцидента для проверки шаблона"
+	return models.Incident{
+		ID:          id,
+		Title:       title,
+		Description: &description,
+		Latitude:    lat,
+		Longitude:   lng,
+		Radius:      500,
+		Category:    category,
+		Severity:    severity,
+		IsActive:    true,
+		Language:    "ru",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"geowarns/internal/geo"
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+var ErrInvalidReport = errors.New("invalid report")
+
+const (
+	maxReportTextLength = 2000
+	minApprovedRadius   = 200.0
+)
+
+// ReportClusterConfig задает, какие сообщения считаются сообщениями об одном и том же
+type ReportClusterConfig struct {
+	RadiusMeters float64
+	Window       time.Duration
+}
+
+type ReportService struct {
+	reportRepo        *repository.ReportRepository
+	attachmentService *AttachmentService
+	subscriberService *WebhookSubscriberService
+	config            ReportClusterConfig
+	logger            *zap.Logger
+}
+
+func NewReportService(
+	reportRepo *repository.ReportRepository,
+	attachmentService *AttachmentService,
+	subscriberService *WebhookSubscriberService,
+	config ReportClusterConfig,
+	logger *zap.Logger,
+) *ReportService {
+	return &ReportService{
+		reportRepo:        reportRepo,
+		attachmentService: attachmentService,
+		subscriberService: subscriberService,
+		config:            config,
+		logger:            logger,
+	}
+}
+
+// SubmitReport сохраняет сообщение пользователя (и фото, если оно есть) и ставит его в очередь модерации
+func (s *ReportService) SubmitReport(ctx context.Context, req *models.IncidentReportRequest, photo io.Reader) (*models.IncidentReport, *models.ReportCluster, error) {
+	text := strings.TrimSpace(req.Text)
+	category := strings.TrimSpace(req.Category)
+
+	switch {
+	case strings.TrimSpace(req.UserID) == "":
+		return nil, nil, fmt.Errorf("%w: user_id is required", ErrInvalidReport)
+	case req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180:
+		return nil, nil, fmt.Errorf("%w: invalid coordinates", ErrInvalidReport)
+	case !models.IsValidCategory(category):
+		return nil, nil, fmt.Errorf("%w: unknown category %q", ErrInvalidReport, req.Category)
+	case text == "":
+		return nil, nil, fmt.Errorf("%w: text is required", ErrInvalidReport)
+	case len([]rune(text)) > maxReportTextLength:
+		return nil, nil, fmt.Errorf("%w: text is longer than %d characters", ErrInvalidReport, maxReportTextLength)
+	}
+
+	report := &models.IncidentReport{
+		UserID:    req.UserID,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Category:  category,
+		Text:      text,
+		Status:    models.ReportStatusPending,
+		CreatedAt: time.Now(),
+	}
+
+	token, err := generateReportToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	tokenHash := hashReportToken(token)
+	report.AccessTokenHash = &tokenHash
+
+	if photo != nil {
+		key, contentType, err := s.attachmentService.StoreImage(ctx, "reports", photo)
+		if err != nil {
+			return nil, nil, err
+		}
+		report.PhotoKey = &key
+		report.PhotoType = &contentType
+		report.HasPhoto = true
+	}
+
+	cluster, err := s.reportRepo.CreateReport(report, s.config.RadiusMeters, s.config.Window)
+	if err != nil {
+		if report.PhotoKey != nil {
+			s.attachmentService.DeleteBlob(ctx, *report.PhotoKey)
+		}
+		return nil, nil, err
+	}
+
+	report.AccessToken = token
+	return report, cluster, nil
+}
+
+func (s *ReportService) GetReport(id uint) (*models.IncidentReport, error) {
+	return s.reportRepo.GetReportByID(id)
+}
+
+// CheckReportToken - совпадает ли token с токеном автора сообщения. У сообщений,
+// отправленных до появления токенов, его нет, их читают только модераторы.
+func (s *ReportService) CheckReportToken(report *models.IncidentReport, token string) bool {
+	if report.AccessTokenHash == nil || token == "" {
+		return false
+	}
+	hash := hashReportToken(token)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(*report.AccessTokenHash)) == 1
+}
+
+// OpenReportPhoto возвращает фото из сообщения; nil - фото нет
+func (s *ReportService) OpenReportPhoto(ctx context.Context, report *models.IncidentReport) (io.ReadCloser, error) {
+	if report.PhotoKey == nil {
+		return nil, nil
+	}
+	return s.attachmentService.OpenBlob(ctx, *report.PhotoKey)
+}
+
+func (s *ReportService) ListClusters(status string, limit, offset int) ([]models.ReportCluster, int64, error) {
+	if status == "" {
+		status = models.ReportStatusPending
+	}
+	if limit <= 0 {
+		limit = models.DefaultPageLimit
+	}
+	if limit > models.MaxPageLimit {
+		limit = models.MaxPageLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.reportRepo.ListClusters(status, limit, offset)
+}
+
+func (s *ReportService) GetCluster(id uint) (*models.ReportCluster, error) {
+	return s.reportRepo.GetCluster(id)
+}
+
+// Approve превращает кластер сообщений в новый инцидент. Заголовок, радиус и язык
+// можно задать явно, иначе они выводятся из сообщений кластера.
+func (s *ReportService) Approve(ctx context.Context, clusterID uint, req *models.ReportApproveRequest) (*models.ReportCluster, error) {
+	cluster, err := s.reportRepo.GetCluster(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Status != models.ReportStatusPending {
+		return nil, repository.ErrClusterNotPending
+	}
+
+	lang, err := normalizeIncidentLanguage(req.Language)
+	if err != nil {
+		return nil, err
+	}
+	severity, err := normalizeIncidentSeverity(req.Severity)
+	if err != nil {
+		return nil, err
+	}
+
+	incident := &models.Incident{
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		Latitude:    cluster.Latitude,
+		Longitude:   cluster.Longitude,
+		Radius:      req.Radius,
+		Category:    cluster.Category,
+		Severity:    severity,
+		Language:    lang,
+		IsActive:    true,
+	}
+	if incident.Title == "" {
+		incident.Title = defaultReportTitle(cluster)
+	}
+	if incident.Description == nil {
+		description := joinReportTexts(cluster)
+		incident.Description = &description
+	}
+	if incident.Radius <= 0 {
+		incident.Radius = clusterRadius(cluster)
+	}
+
+	events := s.subscriberService.IncidentEvents(models.EventIncidentCreated, nil)
+	approved, err := s.reportRepo.Approve(clusterID, incident, req.Moderator, events)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachReportPhotos(ctx, incident.ID, approved)
+	return approved, nil
+}
+
+// Merge присоединяет кластер к существующему инциденту, фото из сообщений становятся вложениями
+func (s *ReportService) Merge(ctx context.Context, clusterID uint, req *models.ReportMergeRequest) (*models.ReportCluster, error) {
+	if req.IncidentID == 0 {
+		return nil, fmt.Errorf("%w: incident_id is required", ErrInvalidReport)
+	}
+
+	merged, err := s.reportRepo.Merge(clusterID, req.IncidentID, req.Moderator)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachReportPhotos(ctx, req.IncidentID, merged)
+	return merged, nil
+}
+
+func (s *ReportService) Reject(clusterID uint, req *models.ReportRejectRequest) (*models.ReportCluster, error) {
+	return s.reportRepo.Reject(clusterID, req.Moderator, req.Reason)
+}
+
+func (s *ReportService) attachReportPhotos(ctx context.Context, incidentID uint, cluster *models.ReportCluster) {
+	for _, report := range cluster.Reports {
+		if report.PhotoKey == nil {
+			continue
+		}
+		fileName := fmt.Sprintf("report-%d%s", report.ID, filepath.Ext(*report.PhotoKey))
+		if _, err := s.attachmentService.ImportBlob(ctx, incidentID, *report.PhotoKey, fileName); err != nil {
+			s.logger.Warn("Failed to attach report photo",
+				zap.Uint("report_id", report.ID),
+				zap.Uint("incident_id", incidentID),
+				zap.Error(err))
+		}
+	}
+}
+
+func defaultReportTitle(cluster *models.ReportCluster) string {
+	title := cluster.Category
+	if len(cluster.Reports) > 0 {
+		title = cluster.Reports[0].Text
+	}
+	if runes := []rune(title); len(runes) > 255 {
+		title = string(runes[:252]) + "..."
+	}
+	return title
+}
+
+func joinReportTexts(cluster *models.ReportCluster) string {
+	texts := make([]string, 0, len(cluster.Reports))
+	for _, report := range cluster.Reports {
+		texts = append(texts, report.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// clusterRadius - радиус, покрывающий все сообщения кластера, но не меньше minApprovedRadius
+func clusterRadius(cluster *models.ReportCluster) float64 {
+	radius := minApprovedRadius
+	for _, report := range cluster.Reports {
+		d := geo.DistanceMeters(cluster.Latitude, cluster.Longitude, report.Latitude, report.Longitude)
+		radius = math.Max(radius, d+50)
+	}
+	return math.Round(radius)
+}
+
+func generateReportToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "rpt_" + hex.EncodeToString(buf), nil
+}
+
+func hashReportToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"time"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// RetentionConfig задает очистку таблиц: правила, размер пачки удаления и паузу
+// между пачками, чтобы не мешать основной нагрузке. При DryRun плановые запуски
+// только подсчитывают строки, которые были бы удалены.
+type RetentionConfig struct {
+	Interval   time.Duration
+	BatchSize  int
+	BatchPause time.Duration
+	DryRun     bool
+	Policies   []models.RetentionPolicy
+}
+
+func DefaultRetentionConfig() RetentionConfig {
+	return RetentionConfig{
+		Interval:   time.Hour,
+		BatchSize:  1000,
+		BatchPause: 100 * time.Millisecond,
+	}
+}
+
+type RetentionService struct {
+	repo   *repository.RetentionRepository
+	config RetentionConfig
+	logger *zap.Logger
+}
+
+func NewRetentionService(repo *repository.RetentionRepository, config RetentionConfig, logger *zap.Logger) *RetentionService {
+	return &RetentionService{
+		repo:   repo,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (s *RetentionService) Policies() []models.RetentionPolicy {
+	return s.config.Policies
+}
+
+// RunScheduled - плановый запуск с режимом из конфигурации
+func (s *RetentionService) RunScheduled() []models.RetentionRun {
+	return s.Run(s.config.DryRun)
+}
+
+// Run применяет все правила по очереди. Ошибка одного правила не останавливает
+// остальные и записывается в результат.
+func (s *RetentionService) Run(dryRun bool) []models.RetentionRun {
+	runs := make([]models.RetentionRun, 0, len(s.config.Policies))
+	for _, policy := range s.config.Policies {
+		run := s.apply(policy, dryRun)
+		if err := s.repo.SaveRun(&run); err != nil {
+			s.logger.Error("Failed to save retention run", zap.String("table", policy.Table), zap.Error(err))
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+func (s *RetentionService) apply(policy models.RetentionPolicy, dryRun bool) models.RetentionRun {
+	started := time.Now()
+	run := models.RetentionRun{Table: policy.Table, DryRun: dryRun, StartedAt: started}
+
+	err := s.purge(policy, dryRun, &run)
+	run.DurationMs = time.Since(started).Milliseconds()
+
+	fields := []zap.Field{
+		zap.String("table", policy.Table),
+		zap.Bool("dry_run", dryRun),
+		zap.Int64("rows", run.Rows),
+		zap.Int("batches", run.Batches),
+		zap.Int64("duration_ms", run.DurationMs),
+	}
+	if err != nil {
+		message := err.Error()
+		run.Error = &message
+		s.logger.Error("Retention run failed", append(fields, zap.Error(err))...)
+	} else if run.Rows > 0 {
+		s.logger.Info("Retention run finished", fields...)
+	}
+	return run
+}
+
+func (s *RetentionService) purge(policy models.RetentionPolicy, dryRun bool, run *models.RetentionRun) error {
+	scope, err := s.repo.Scope(policy, time.Now())
+	if err != nil || scope == nil {
+		return err
+	}
+	if dryRun {
+		run.Rows, err = s.repo.Count(scope)
+		return err
+	}
+
+	for {
+		deleted, err := s.repo.DeleteBatch(scope, s.config.BatchSize)
+		if err != nil {
+			return err
+		}
+		run.Rows += deleted
+		run.Batches++
+		if deleted < int64(s.config.BatchSize) {
+			return nil
+		}
+		time.Sleep(s.config.BatchPause)
+	}
+}
+
+func (s *RetentionService) Runs(table string, limit int) ([]models.RetentionRun, error) {
+	if limit <= 0 {
+		limit = models.DefaultPageLimit
+	}
+	if limit > models.MaxPageLimit {
+		limit = models.MaxPageLimit
+	}
+	return s.repo.ListRuns(table, limit)
+}
+
+func (s *RetentionService) Stats() ([]models.RetentionTableStats, error) {
+	return s.repo.GetStats()
+}
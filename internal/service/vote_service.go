@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"geowarns/internal/geo"
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+)
+
+var (
+	ErrInvalidVote      = errors.New("invalid vote")
+	ErrUserNotAlerted   = errors.New("user was not alerted about this incident")
+	ErrIncidentInactive = errors.New("incident is not active")
+)
+
+const (
+	VoteActionFlag       = "flag"
+	VoteActionDeactivate = "deactivate"
+)
+
+// VotePolicy определяет, когда опровержения перевешивают подтверждения
+// и что в этом случае делать с инцидентом
+type VotePolicy struct {
+	// Action - flag (только пометить на проверку оператору) или deactivate (еще и отключить)
+	Action string
+	// MinVotes - минимальное число голосов, после которого применяется политика
+	MinVotes int
+	// DisputeRatio - доля веса опровержений от общего веса, при которой срабатывает политика
+	DisputeRatio float64
+	// FarVoteWeight - вес голоса, отданного за пределами радиуса инцидента
+	FarVoteWeight float64
+}
+
+func DefaultVotePolicy() VotePolicy {
+	return VotePolicy{
+		Action:        VoteActionFlag,
+		MinVotes:      5,
+		DisputeRatio:  0.6,
+		FarVoteWeight: 0.5,
+	}
+}
+
+type VoteService struct {
+	voteRepo          *repository.VoteRepository
+	incidentRepo      *repository.IncidentRepository
+	webhookTaskRepo   *repository.WebhookTaskRepository
+	subscriberService *WebhookSubscriberService
+	policy            VotePolicy
+}
+
+func NewVoteService(
+	voteRepo *repository.VoteRepository,
+	incidentRepo *repository.IncidentRepository,
+	webhookTaskRepo *repository.WebhookTaskRepository,
+	subscriberService *WebhookSubscriberService,
+	policy VotePolicy,
+) *VoteService {
+	return &VoteService{
+		voteRepo:          voteRepo,
+		incidentRepo:      incidentRepo,
+		webhookTaskRepo:   webhookTaskRepo,
+		subscriberService: subscriberService,
+		policy:            policy,
+	}
+}
+
+// CastVote принимает голос оповещенного пользователя и пересчитывает уверенность по инциденту
+func (s *VoteService) CastVote(incidentID uint, req *models.IncidentVoteRequest) (*models.IncidentVoteSummary, error) {
+	vote := strings.ToLower(strings.TrimSpace(req.Vote))
+	switch {
+	case strings.TrimSpace(req.UserID) == "":
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidVote)
+	case vote != models.VoteConfirm && vote != models.VoteDispute:
+		return nil, fmt.Errorf("%w: vote must be %q or %q", ErrInvalidVote, models.VoteConfirm, models.VoteDispute)
+	case req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180:
+		return nil, fmt.Errorf("%w: invalid coordinates", ErrInvalidVote)
+	}
+
+	incident, err := s.incidentRepo.GetByID(incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if !incident.IsActive {
+		return nil, ErrIncidentInactive
+	}
+
+	alerted, err := s.webhookTaskRepo.WasUserAlerted(incidentID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !alerted {
+		return nil, ErrUserNotAlerted
+	}
+
+	distance := geo.DistanceMeters(req.Latitude, req.Longitude, incident.Latitude, incident.Longitude)
+	weight := 1.0
+	if distance > incident.Radius {
+		weight = s.policy.FarVoteWeight
+	}
+
+	return s.voteRepo.CastVote(&models.IncidentVote{
+		IncidentID:     incidentID,
+		UserID:         req.UserID,
+		Vote:           vote,
+		Latitude:       req.Latitude,
+		Longitude:      req.Longitude,
+		DistanceMeters: math.Round(distance),
+		Weight:         weight,
+	}, s.evaluate, s.subscriberService.IncidentEvents(models.EventIncidentResolved, models.JSON{
+		"changes": models.JSON{
+			"is_active": models.JSON{"old": true, "new": false},
+		},
+		"reason": "disputed_by_votes",
+	}))
+}
+
+func (s *VoteService) GetSummary(incidentID uint) (*models.IncidentVoteSummary, []models.IncidentVote, error) {
+	summary, err := s.voteRepo.GetSummary(incidentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	votes, err := s.voteRepo.GetVotes(incidentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return summary, votes, nil
+}
+
+// evaluate считает уверенность как сглаженную долю веса подтверждений
+// (без голосов она равна 0.5) и применяет политику
+func (s *VoteService) evaluate(summary *models.IncidentVoteSummary) {
+	total := summary.ConfirmWeight + summary.DisputeWeight
+	summary.Confidence = math.Round((summary.ConfirmWeight+1)/(total+2)*1000) / 1000
+
+	if summary.Confirmations+summary.Disputes < s.policy.MinVotes || total == 0 {
+		return
+	}
+	if summary.DisputeWeight/total < s.policy.DisputeRatio {
+		return
+	}
+
+	summary.NeedsReview = true
+	if s.policy.Action == VoteActionDeactivate && summary.IsActive {
+		summary.AutoDeactivated = true
+	}
+}
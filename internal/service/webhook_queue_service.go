@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+)
+
+var (
+	ErrInvalidTaskAction = errors.New("invalid webhook task action")
+	// ErrEmptyTaskFilter - массовое действие без фильтра затронуло бы всю очередь
+	ErrEmptyTaskFilter = errors.New("bulk action requires at least one filter")
+)
+
+// WebhookQueueService - администрирование очереди вебхуков: статистика, просмотр,
+// отмена, повтор и удаление задач
+type WebhookQueueService struct {
+	repo *repository.WebhookTaskRepository
+}
+
+func NewWebhookQueueService(repo *repository.WebhookTaskRepository) *WebhookQueueService {
+	return &WebhookQueueService{repo: repo}
+}
+
+func (s *WebhookQueueService) Stats() (*models.WebhookQueueStats, error) {
+	counts, err := s.repo.GetStats()
+	if err != nil {
+		return nil, err
+	}
+	age, err := s.repo.GetAgeStats()
+	if err != nil {
+		return nil, err
+	}
+	oldest, err := s.repo.GetOldestPending()
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebhookQueueStats{
+		Counts:        counts,
+		Age:           age,
+		OldestPending: oldest,
+	}, nil
+}
+
+func (s *WebhookQueueService) List(filter models.WebhookTaskFilter, page models.PageRequest) ([]models.WebhookTask, models.PageInfo, error) {
+	if filter.Status != "" && !isTaskStatus(filter.Status) {
+		return nil, models.PageInfo{}, fmt.Errorf("%w: unknown status %q", ErrInvalidTaskAction, filter.Status)
+	}
+	return s.repo.List(filter, page)
+}
+
+// Apply выполняет действие над одной задачей
+func (s *WebhookQueueService) Apply(id uint, action string, actor models.TaskActor) error {
+	if _, err := s.repo.GetTaskByID(id); err != nil {
+		return err
+	}
+	n, err := s.apply(action, models.WebhookTaskFilter{ID: &id}, actor)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrTaskStatusConflict
+	}
+	return nil
+}
+
+// Bulk выполняет действие над всеми задачами, подходящими под фильтр, и возвращает их число
+func (s *WebhookQueueService) Bulk(req *models.WebhookTaskBulkRequest) (int64, error) {
+	filter := req.WebhookTaskFilter
+	filter.ID = nil
+	filter.Status = req.Status
+	if filter.Status != "" && !isTaskStatus(filter.Status) {
+		return 0, fmt.Errorf("%w: unknown status %q", ErrInvalidTaskAction, filter.Status)
+	}
+	if filter.IsEmpty() {
+		return 0, ErrEmptyTaskFilter
+	}
+	return s.apply(req.Action, filter, req.Actor)
+}
+
+func (s *WebhookQueueService) apply(action string, filter models.WebhookTaskFilter, actor models.TaskActor) (int64, error) {
+	switch action {
+	case models.TaskActionCancel:
+		return s.repo.CancelTasks(filter, actor)
+	case models.TaskActionRetry:
+		return s.repo.RetryTasks(filter, actor)
+	case models.TaskActionDelete:
+		return s.repo.DeleteTasks(filter)
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidTaskAction, action)
+}
+
+func isTaskStatus(status string) bool {
+	switch status {
+	case "pending", "processing", "completed", "failed", "discarded":
+		return true
+	}
+	return false
+}
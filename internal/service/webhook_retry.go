@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy задает повторные попытки доставки вебхука
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    time.Hour,
+	}
+}
+
+// Backoff возвращает задержку перед следующей попыткой после attempts неудачных:
+// экспоненциальный рост от BaseDelay с ограничением MaxDelay и случайным разбросом
+// в пределах второй половины интервала, чтобы повторы не приходили пачкой
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempts-1))
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	half := delay / 2
+	return time.Duration(half + rand.Float64()*half)
+}
+
+// NextDelay учитывает Retry-After получателя, но не дольше MaxDelay
+func (p RetryPolicy) NextDelay(attempts int, err *DeliveryError) time.Duration {
+	if err != nil && err.RetryAfter > 0 {
+		if err.RetryAfter > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return err.RetryAfter
+	}
+	return p.Backoff(attempts)
+}
+
+// DeliveryError - ошибка доставки вебхука с признаком, стоит ли повторять попытку
+type DeliveryError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Retryable  bool
+	Err        error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+func permanentError(format string, args ...interface{}) *DeliveryError {
+	return &DeliveryError{Err: fmt.Errorf(format, args...)}
+}
+
+// transportError - ошибка сети или таймаут: их имеет смысл повторять
+func transportError(err error) *DeliveryError {
+	return &DeliveryError{Retryable: true, Err: fmt.Errorf("failed to send request: %w", err)}
+}
+
+// statusError классифицирует ответ получателя: 5xx, 408 и 429 - временные ошибки,
+// остальные 4xx - постоянные
+func statusError(resp *http.Response) *DeliveryError {
+	err := &DeliveryError{
+		StatusCode: resp.StatusCode,
+		Err:        fmt.Errorf("webhook returned status: %d", resp.StatusCode),
+	}
+	switch {
+	case resp.StatusCode >= 500,
+		resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests:
+		err.Retryable = true
+		err.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return err
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в виде HTTP-даты
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// asDeliveryError приводит произвольную ошибку к DeliveryError.
+// Неизвестные ошибки считаются временными.
+func asDeliveryError(err error) *DeliveryError {
+	var de *DeliveryError
+	if errors.As(err, &de) {
+		return de
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return transportError(err)
+	}
+	return &DeliveryError{Retryable: true, Err: err}
+}
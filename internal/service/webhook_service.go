@@ -2,108 +2,525 @@ package service
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"geowarns/internal/models"
 	repository "geowarns/internal/repository"
+	"geowarns/pkg/webhooksig"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+const (
+	// WebhookRequestTimeout - таймаут одного запроса к получателю
+	WebhookRequestTimeout = 30 * time.Second
+	// deliveryLogBodyLimit - сколько байт ответа получателя сохраняется в журнале доставки
+	deliveryLogBodyLimit = 4 * 1024
+	// RateLimitMaxWait - сколько обработчик ждет своей очереди по max_rps получателя;
+	// если дольше, задача возвращается в очередь
+	RateLimitMaxWait = 5 * time.Second
+	// LeaseMargin - запас аренды сверх ожидания и запроса на запись результата в базу
+	LeaseMargin = 10 * time.Second
+)
+
+// MinWorkerLease - нижняя граница аренды: одна отправка может занять RateLimitMaxWait
+// на ожидание, WebhookRequestTimeout на запрос и еще время на запись результата
+const MinWorkerLease = WebhookRequestTimeout + RateLimitMaxWait + LeaseMargin
+
+// WorkerConfig задает размер пула обработчиков и время аренды задачи. Аренда
+// должна быть больше MinWorkerLease, иначе задачу заберут повторно,
+// пока она еще отправляется.
+type WorkerConfig struct {
+	Workers int
+	Lease   time.Duration
+}
+
+func DefaultWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		Workers: 10,
+		Lease:   2 * time.Minute,
+	}
+}
+
 type WebhookService struct {
-	repo       *repository.WebhookTaskRepository
-	httpClient *http.Client
-	webhookURL string
-	logger     *zap.Logger
+	repo           *repository.WebhookTaskRepository
+	subscriberRepo *repository.WebhookSubscriberRepository
+	attemptRepo    *repository.DeliveryAttemptRepository
+	digestRepo     *repository.DigestRepository
+	breaker        *CircuitBreaker
+	rateRepo       *repository.WebhookRateRepository
+	httpClient     *http.Client
+	webhookURL     string
+	secrets        []string
+	retryPolicy    RetryPolicy
+	workers        WorkerConfig
+	owner          string
+	slots          chan struct{}
+	wg             sync.WaitGroup
+	logger         *zap.Logger
 }
 
 func NewWebhookService(
 	repo *repository.WebhookTaskRepository,
+	subscriberRepo *repository.WebhookSubscriberRepository,
+	attemptRepo *repository.DeliveryAttemptRepository,
+	digestRepo *repository.DigestRepository,
+	breaker *CircuitBreaker,
+	rateRepo *repository.WebhookRateRepository,
 	webhookURL string,
+	secrets []string,
+	retryPolicy RetryPolicy,
+	workers WorkerConfig,
 	logger *zap.Logger,
 ) *WebhookService {
 	return &WebhookService{
-		repo:       repo,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
-		webhookURL: webhookURL,
-		logger:     logger,
+		repo:           repo,
+		subscriberRepo: subscriberRepo,
+		attemptRepo:    attemptRepo,
+		digestRepo:     digestRepo,
+		breaker:        breaker,
+		rateRepo:       rateRepo,
+		httpClient:     &http.Client{Timeout: WebhookRequestTimeout},
+		webhookURL:     webhookURL,
+		secrets:        secrets,
+		retryPolicy:    retryPolicy,
+		workers:        workers,
+		owner:          workerOwner(),
+		slots:          make(chan struct{}, workers.Workers),
+		logger:         logger,
 	}
 }
 
+// ProcessPendingTasks забирает столько готовых задач, сколько в пуле свободных
+// обработчиков, и отправляет их. Забранные задачи находятся в processing и не
+// будут взяты повторно ни этим, ни другим экземпляром приложения, пока действует аренда.
 func (s *WebhookService) ProcessPendingTasks() error {
-	tasks, err := s.repo.GetPendingTasks()
+	free := cap(s.slots) - len(s.slots)
+	if free <= 0 {
+		return nil
+	}
+
+	tasks, err := s.repo.ClaimTasks(s.owner, free, s.workers.Lease)
 	if err != nil {
-		return fmt.Errorf("failed to get pending tasks: %w", err)
+		return fmt.Errorf("failed to claim pending tasks: %w", err)
 	}
 
 	for _, task := range tasks {
+		s.slots <- struct{}{}
+		s.wg.Add(1)
 		go func(t models.WebhookTask) {
-			if err := s.sendWebhook(t); err != nil {
-				s.logger.Error("Failed to send webhook",
-					zap.Uint("task_id", t.ID),
-					zap.Error(err))
-				if err := s.repo.UpdateStatus(t.ID, "failed"); err != nil {
-					s.logger.Error("Failed to update task status",
-						zap.Uint("task_id", t.ID),
-						zap.Error(err))
-				}
-				return
-			}
-
-			if err := s.repo.UpdateStatus(t.ID, "completed"); err != nil {
-				s.logger.Error("Failed to update task status",
-					zap.Uint("task_id", t.ID),
-					zap.Error(err))
-			}
+			defer func() {
+				<-s.slots
+				s.wg.Done()
+			}()
+			s.deliver(t)
 		}(task)
 	}
 
 	return nil
 }
 
-func (s *WebhookService) sendWebhook(task models.WebhookTask) error {
-	incident, err := s.repo.GetIncidentByID(task.IncidentID)
+// Wait дожидается завершения уже начатых отправок
+func (s *WebhookService) Wait() {
+	s.wg.Wait()
+}
+
+// deliver отправляет вебхук и по результату завершает задачу, откладывает
+// следующую попытку или помечает задачу как failed. Если выключатель получателя
+// разомкнут или получатель отключен, задача возвращается в очередь без попытки.
+func (s *WebhookService) deliver(t models.WebhookTask) {
+	if t.SubscriberID != nil {
+		allowed, until, err := s.breaker.Allow(*t.SubscriberID)
+		if err != nil {
+			// Без состояния выключателя отправка все равно выполняется
+			s.logger.Error("Failed to check webhook circuit",
+				zap.Uint("task_id", t.ID),
+				zap.Error(err))
+			allowed = true
+		}
+		if !allowed {
+			s.postpone(t, until)
+			return
+		}
+	}
+
+	attempts := t.Attempts + 1
+
+	record := &models.WebhookDeliveryAttempt{
+		TaskID:       t.ID,
+		SubscriberID: t.SubscriberID,
+		Attempt:      attempts,
+	}
+	err := s.sendWebhook(t, record)
+	var pe *postponeError
+	if errors.As(err, &pe) {
+		s.postpone(t, pe.until)
+		return
+	}
+	s.saveAttempt(record, err)
+	if t.SubscriberID != nil {
+		s.breaker.Record(*t.SubscriberID, err)
+	}
+
+	if err == nil {
+		if err := s.repo.MarkCompleted(t.ID, s.owner, attempts); err != nil {
+			s.logFinishError(t, err)
+		}
+		return
+	}
+
+	de := asDeliveryError(err)
+	if de.Retryable && attempts < s.retryPolicy.MaxAttempts {
+		delay := s.retryPolicy.NextDelay(attempts, de)
+		s.logger.Warn("Webhook delivery failed, will retry",
+			zap.Uint("task_id", t.ID),
+			zap.Int("attempts", attempts),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+		if err := s.repo.ScheduleRetry(t.ID, s.owner, attempts, time.Now().Add(delay), err.Error()); err != nil {
+			s.logFinishError(t, err)
+		}
+		return
+	}
+
+	s.logger.Error("Failed to send webhook",
+		zap.Uint("task_id", t.ID),
+		zap.Int("attempts", attempts),
+		zap.Bool("retryable", de.Retryable),
+		zap.Error(err))
+	if err := s.repo.MarkFailed(t.ID, s.owner, attempts, err.Error()); err != nil {
+		s.logFinishError(t, err)
+	}
+}
+
+// postpone возвращает задачу в очередь до until, не засчитывая попытку
+func (s *WebhookService) postpone(t models.WebhookTask, until time.Time) {
+	s.logger.Debug("Webhook delivery postponed",
+		zap.Uint("task_id", t.ID),
+		zap.Time("until", until))
+	if err := s.repo.Postpone(t.ID, s.owner, until); err != nil {
+		s.logFinishError(t, err)
+	}
+}
+
+func (s *WebhookService) logFinishError(t models.WebhookTask, err error) {
+	if errors.Is(err, repository.ErrLeaseLost) {
+		// Аренда истекла, и задачу уже забрал другой обработчик - результат этой попытки отбрасывается
+		s.logger.Warn("Task lease expired before delivery finished",
+			zap.Uint("task_id", t.ID),
+			zap.String("owner", s.owner))
+		return
+	}
+	s.logger.Error("Failed to update task status",
+		zap.Uint("task_id", t.ID),
+		zap.Error(err))
+}
+
+// workerOwner - идентификатор экземпляра приложения для аренды задач
+func workerOwner() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	suffix, err := randomID()
 	if err != nil {
-		return fmt.Errorf("failed to get incident: %w", err)
+		suffix = fmt.Sprint(time.Now().UnixNano())
 	}
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), suffix[:8])
+}
 
-	payload := map[string]interface{}{
-		"event":     "user_near_incident",
-		"incident":  incident,
-		"user_id":   task.UserID,
-		"timestamp": time.Now().Format(time.RFC3339),
+// RenderPayload возвращает тело, которое будет отправлено по задаче, с учетом шаблона
+// и конверта получателя
+func (s *WebhookService) RenderPayload(task models.WebhookTask) (json.RawMessage, error) {
+	target := &deliveryTarget{envelope: models.EnvelopeNone}
+	if task.SubscriberID != nil {
+		subscriber, err := s.subscriberRepo.GetByID(*task.SubscriberID)
+		if err != nil {
+			return nil, err
+		}
+		target.template = subscriber.PayloadTemplate
+		target.envelope = subscriber.Envelope
 	}
+	body, _, err := s.renderBody(task, target)
+	return body, err
+}
 
-	jsonPayload, err := json.Marshal(payload)
+// renderBody собирает тело запроса: стандартное тело, шаблон получателя и конверт.
+// Возвращает также заголовки конверта, включая Content-Type.
+func (s *WebhookService) renderBody(task models.WebhookTask, target *deliveryTarget) ([]byte, http.Header, error) {
+	payload, err := s.buildPayload(task)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return nil, nil, err
+	}
+	if payload, err = applyTemplate(target.template, payload); err != nil {
+		return nil, nil, err
 	}
+	return wrapEnvelope(task, target.envelope, payload)
+}
 
-	webhookURL, err := url.Parse(s.webhookURL)
+// sendWebhook отправляет вебхук и заполняет запись журнала доставки
+func (s *WebhookService) sendWebhook(task models.WebhookTask, record *models.WebhookDeliveryAttempt) error {
+	target, err := s.endpoint(task)
 	if err != nil {
-		return fmt.Errorf("invalid webhook URL: %w", err)
+		return err
+	}
+	record.URL = target.url
+	if err := s.throttle(target); err != nil {
+		return err
+	}
+
+	jsonPayload, header, err := s.renderBody(task, target)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(jsonPayload)
+	record.PayloadSHA256 = hex.EncodeToString(sum[:])
+
+	webhookURL, err := url.Parse(target.url)
+	if err != nil {
+		return permanentError("invalid webhook URL: %v", err)
 	}
 
 	req, err := http.NewRequest("POST", webhookURL.String(), bytes.NewBuffer(jsonPayload))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return permanentError("failed to create request: %v", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if len(target.secrets) > 0 {
+		webhooksig.SignRequest(req, target.secrets, jsonPayload, time.Now())
+	}
 
+	record.RequestHeaders = headersToJSON(req.Header)
+
+	started := time.Now()
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		record.LatencyMs = time.Since(started).Milliseconds()
+		return transportError(err)
 	}
 	defer resp.Body.Close()
 
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, deliveryLogBodyLimit))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	record.LatencyMs = time.Since(started).Milliseconds()
+	record.ResponseStatus = &resp.StatusCode
+	if len(body) > 0 {
+		text := strings.ToValidUTF8(string(body), "\uFFFD")
+		record.ResponseBody = &text
+	}
+
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+		return statusError(resp)
 	}
 
 	return nil
 }
+
+// saveAttempt сохраняет запись журнала доставки. Ошибка записи не должна
+// влиять на саму доставку, поэтому только логируется.
+func (s *WebhookService) saveAttempt(record *models.WebhookDeliveryAttempt, deliveryErr error) {
+	if deliveryErr != nil {
+		message := deliveryErr.Error()
+		record.Error = &message
+	}
+	record.CreatedAt = time.Now()
+	if err := s.attemptRepo.Create(record); err != nil {
+		s.logger.Error("Failed to save delivery attempt",
+			zap.Uint("task_id", record.TaskID),
+			zap.Error(err))
+	}
+}
+
+// headersToJSON - заголовки запроса для журнала доставки. Подпись не сохраняется:
+// журнал не должен позволять повторить подписанный запрос.
+func headersToJSON(header http.Header) models.JSON {
+	result := make(models.JSON, len(header))
+	for name, values := range header {
+		if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(webhooksig.SignatureHeader) {
+			continue
+		}
+		result[name] = strings.Join(values, ", ")
+	}
+	return result
+}
+
+// postponeError - отправка не выполнялась, задача возвращается в очередь до until
+// без учета попытки: получателя отключили после того, как задача была забрана,
+// или он исчерпал ограничение частоты запросов
+type postponeError struct {
+	until  time.Time
+	reason string
+}
+
+func (e *postponeError) Error() string {
+	return e.reason
+}
+
+// deliveryTarget - куда и в каком виде отправляется задача
+type deliveryTarget struct {
+	subscriberID uint
+	url          string
+	secrets      []string
+	template     *string
+	envelope     string
+	maxRPS       *float64
+}
+
+// throttle дожидается очереди на отправку по max_rps получателя. Если ждать
+// дольше RateLimitMaxWait, резерв отменяется и задача откладывается.
+func (s *WebhookService) throttle(target *deliveryTarget) error {
+	if target.maxRPS == nil {
+		return nil
+	}
+	rate := *target.maxRPS
+	wait, err := s.rateRepo.Reserve(target.subscriberID, rate, math.Max(1, rate))
+	if err != nil {
+		return fmt.Errorf("failed to reserve rate limit: %w", err)
+	}
+	if wait > RateLimitMaxWait {
+		if err := s.rateRepo.Cancel(target.subscriberID); err != nil {
+			s.logger.Error("Failed to cancel rate limit reservation",
+				zap.Uint("subscriber_id", target.subscriberID),
+				zap.Error(err))
+		}
+		return &postponeError{
+			until:  time.Now().Add(wait),
+			reason: fmt.Sprintf("subscriber %d rate limit exceeded", target.subscriberID),
+		}
+	}
+	time.Sleep(wait)
+	return nil
+}
+
+// endpoint возвращает адрес, секреты подписи и шаблон тела получателя задачи. Задачи
+// без получателя (созданные до появления подписчиков) отправляются на WEBHOOK_URL.
+func (s *WebhookService) endpoint(task models.WebhookTask) (*deliveryTarget, error) {
+	if task.SubscriberID == nil {
+		if s.webhookURL == "" {
+			return nil, permanentError("task has no subscriber and WEBHOOK_URL is not set")
+		}
+		return &deliveryTarget{url: s.webhookURL, secrets: s.secrets, envelope: models.EnvelopeNone}, nil
+	}
+
+	subscriber, err := s.subscriberRepo.GetByID(*task.SubscriberID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, permanentError("subscriber %d not found", *task.SubscriberID)
+		}
+		return nil, fmt.Errorf("failed to get subscriber: %w", err)
+	}
+	if !subscriber.Enabled {
+		return nil, &postponeError{until: time.Now(), reason: fmt.Sprintf("subscriber %d is disabled", subscriber.ID)}
+	}
+	return &deliveryTarget{
+		subscriberID: subscriber.ID,
+		url:          subscriber.URL,
+		secrets:      subscriber.Secrets(),
+		template:     subscriber.PayloadTemplate,
+		envelope:     subscriber.Envelope,
+		maxRPS:       subscriber.MaxRPS,
+	}, nil
+}
+
+func (s *WebhookService) buildPayload(task models.WebhookTask) ([]byte, error) {
+	var payload map[string]interface{}
+	var err error
+
+	switch task.Event {
+	case models.EventUserNearIncidents:
+		payload, err = s.buildCombinedPayload(task)
+	case models.EventDigest:
+		payload, err = s.buildDigestPayload(task)
+	case models.EventIncidentDeleted:
+		payload, err = deletedIncidentPayload(task)
+	default:
+		payload, err = s.buildIncidentPayload(task)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload["timestamp"] = time.Now().Format(time.RFC3339)
+	return marshalPayload(payload)
+}
+
+func (s *WebhookService) buildIncidentPayload(task models.WebhookTask) (map[string]interface{}, error) {
+	incident, err := s.repo.GetIncidentByID(task.IncidentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, permanentError("incident %d not found", task.IncidentID)
+		}
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+
+	if task.Language != "" {
+		localizeIncident(incident, []string{task.Language})
+	}
+	return incidentPayload(task, incident), nil
+}
+
+// incidentPayload собирает тело вебхука о событии одного инцидента
+func incidentPayload(task models.WebhookTask, incident *models.Incident) map[string]interface{} {
+	event := task.Event
+	if event == "" {
+		event = models.EventUserNearIncident
+	}
+
+	payload := map[string]interface{}{
+		"event":    event,
+		"incident": incident,
+		"language": incident.Language,
+	}
+	// События жизненного цикла инцидента не относятся к конкретному пользователю
+	if !models.IsIncidentLifecycleEvent(event) {
+		payload["user_id"] = task.UserID
+	}
+	switch event {
+	case models.EventIncidentUpdate:
+		payload["update"] = task.Payload["update"]
+	case models.EventIncidentUpdated, models.EventIncidentResolved:
+		payload["changes"] = task.Payload["changes"]
+		if reason, ok := task.Payload["reason"]; ok {
+			payload["reason"] = reason
+		}
+	}
+	return payload
+}
+
+// deletedIncidentPayload собирает тело incident.deleted: инцидент уже удален,
+// отправляется его копия на момент удаления
+func deletedIncidentPayload(task models.WebhookTask) (map[string]interface{}, error) {
+	snapshot, ok := task.Payload["incident"].(map[string]interface{})
+	if !ok {
+		return nil, permanentError("deleted incident %d has no snapshot", task.IncidentID)
+	}
+	return map[string]interface{}{
+		"event":    task.Event,
+		"incident": snapshot,
+		"language": snapshot["language"],
+	}, nil
+}
+
+func marshalPayload(payload map[string]interface{}) ([]byte, error) {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, permanentError("failed to marshal payload: %v", err)
+	}
+	return jsonPayload, nil
+}
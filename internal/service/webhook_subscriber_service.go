@@ -0,0 +1,398 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"geowarns/internal/models"
+	repository "geowarns/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+var ErrInvalidSubscriber = errors.New("invalid webhook subscriber")
+
+type WebhookSubscriberService struct {
+	repo         *repository.WebhookSubscriberRepository
+	incidentRepo *repository.IncidentRepository
+	circuitRepo  *repository.WebhookCircuitRepository
+	logger       *zap.Logger
+}
+
+func NewWebhookSubscriberService(
+	repo *repository.WebhookSubscriberRepository,
+	incidentRepo *repository.IncidentRepository,
+	circuitRepo *repository.WebhookCircuitRepository,
+	logger *zap.Logger,
+) *WebhookSubscriberService {
+	return &WebhookSubscriberService{
+		repo:         repo,
+		incidentRepo: incidentRepo,
+		circuitRepo:  circuitRepo,
+		logger:       logger,
+	}
+}
+
+// Create регистрирует получателя. Если секрет не передан, он генерируется и
+// возвращается вторым значением - позже его получить нельзя.
+func (s *WebhookSubscriberService) Create(req *models.WebhookSubscriberRequest) (*models.WebhookSubscriber, string, error) {
+	if req.URL == nil {
+		return nil, "", fmt.Errorf("%w: url is required", ErrInvalidSubscriber)
+	}
+
+	subscriber := &models.WebhookSubscriber{
+		Enabled:      true,
+		DeliveryMode: models.DeliveryModeSingle,
+		Envelope:     models.EnvelopeNone,
+	}
+	if err := applySubscriberRequest(subscriber, req); err != nil {
+		return nil, "", err
+	}
+
+	var generated string
+	if subscriber.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, "", err
+		}
+		subscriber.Secret = secret
+		generated = secret
+	}
+
+	if err := s.repo.Create(subscriber); err != nil {
+		return nil, "", err
+	}
+	return subscriber, generated, nil
+}
+
+func (s *WebhookSubscriberService) List() ([]models.WebhookSubscriber, error) {
+	return s.repo.GetAll()
+}
+
+func (s *WebhookSubscriberService) Get(id uint) (*models.WebhookSubscriber, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *WebhookSubscriberService) Update(id uint, req *models.WebhookSubscriberRequest) (*models.WebhookSubscriber, error) {
+	subscriber, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	wasEnabled := subscriber.Enabled
+	if err := applySubscriberRequest(subscriber, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(subscriber); err != nil {
+		return nil, err
+	}
+	// Включение получателя вручную сбрасывает выключатель: отложенные задачи уходят сразу
+	if !wasEnabled && subscriber.Enabled {
+		if err := s.circuitRepo.Reset(subscriber.ID); err != nil {
+			return nil, err
+		}
+	}
+	return subscriber, nil
+}
+
+// GetCircuit возвращает состояние выключателя получателя
+func (s *WebhookSubscriberService) GetCircuit(id uint) (*models.WebhookCircuit, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.circuitRepo.Get(id)
+}
+
+func (s *WebhookSubscriberService) Delete(id uint) error {
+	return s.repo.Delete(id)
+}
+
+func (s *WebhookSubscriberService) GetEnabled() ([]models.WebhookSubscriber, error) {
+	return s.repo.GetEnabled()
+}
+
+// FanOut размножает задачи по инциденту: по одной копии на каждого включенного
+// получателя, чьи фильтры пропускают событие задачи и сам инцидент. Получателям
+// в режиме digest вместо задач достаются записи для сводки.
+func (s *WebhookSubscriberService) FanOut(incident *models.Incident, tasks []models.WebhookTask) (*models.AlertBatch, error) {
+	result := &models.AlertBatch{}
+	if len(tasks) == 0 {
+		return result, nil
+	}
+
+	subscribers, err := s.repo.GetEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscribers: %w", err)
+	}
+
+	for _, task := range tasks {
+		event := task.Event
+		if event == "" {
+			event = models.EventUserNearIncident
+		}
+		for i := range subscribers {
+			if ok, _ := subscribers[i].Match(event, incident); !ok {
+				continue
+			}
+			t := task
+			t.Event = event
+			result.Add(t, &subscribers[i])
+		}
+	}
+	return result, nil
+}
+
+// TestMatch показывает, до каких получателей дошло бы событие по инциденту
+func (s *WebhookSubscriberService) TestMatch(req *models.SubscriberMatchRequest) (*models.Incident, []models.SubscriberMatch, error) {
+	event := strings.TrimSpace(req.Event)
+	if event == "" {
+		event = models.EventUserNearIncident
+	}
+	if !models.IsValidWebhookEvent(event) {
+		return nil, nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscriber, event)
+	}
+
+	var incident *models.Incident
+	if req.IncidentID != nil {
+		var err error
+		if incident, err = s.incidentRepo.GetByID(*req.IncidentID); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180 {
+			return nil, nil, fmt.Errorf("%w: invalid coordinates", ErrInvalidSubscriber)
+		}
+		category, err := normalizeIncidentCategory(req.Category)
+		if err != nil {
+			return nil, nil, err
+		}
+		severity, err := normalizeIncidentSeverity(req.Severity)
+		if err != nil {
+			return nil, nil, err
+		}
+		incident = &models.Incident{
+			Latitude:  req.Latitude,
+			Longitude: req.Longitude,
+			Category:  category,
+			Severity:  severity,
+		}
+	}
+
+	subscribers, err := s.repo.GetAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matches := make([]models.SubscriberMatch, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		matched, reason := subscriber.Match(event, incident)
+		matches = append(matches, models.SubscriberMatch{
+			Subscriber: subscriber,
+			Matched:    matched,
+			Reason:     reason,
+		})
+	}
+	return incident, matches, nil
+}
+
+// EnsureDefault создает получателя из WEBHOOK_URL, если получателей еще нет,
+// чтобы существующие установки продолжили получать вебхуки
+func (s *WebhookSubscriberService) EnsureDefault(webhookURL string, secrets []string) error {
+	if webhookURL == "" {
+		return nil
+	}
+	count, err := s.repo.Count()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	description := "created from WEBHOOK_URL"
+	req := &models.WebhookSubscriberRequest{
+		URL:         &webhookURL,
+		Description: &description,
+	}
+	if len(secrets) > 0 {
+		req.Secret = &secrets[0]
+	}
+	if len(secrets) > 1 {
+		req.PreviousSecret = &secrets[1]
+	}
+
+	subscriber, _, err := s.Create(req)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Default webhook subscriber created",
+		zap.Uint("subscriber_id", subscriber.ID),
+		zap.String("url", subscriber.URL))
+	return nil
+}
+
+func applySubscriberRequest(subscriber *models.WebhookSubscriber, req *models.WebhookSubscriberRequest) error {
+	if req.URL != nil {
+		u, err := url.Parse(strings.TrimSpace(*req.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidSubscriber)
+		}
+		subscriber.URL = u.String()
+	}
+	if req.Secret != nil {
+		subscriber.Secret = strings.TrimSpace(*req.Secret)
+	}
+	if req.PreviousSecret != nil {
+		previous := strings.TrimSpace(*req.PreviousSecret)
+		if previous == "" {
+			subscriber.PreviousSecret = nil
+		} else {
+			subscriber.PreviousSecret = &previous
+		}
+	}
+	if req.EventTypes != nil {
+		events := models.StringList{}
+		for _, event := range *req.EventTypes {
+			event = strings.TrimSpace(event)
+			if !models.IsValidWebhookEvent(event) {
+				return fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscriber, event)
+			}
+			events = append(events, event)
+		}
+		subscriber.EventTypes = events
+	}
+	if req.Region != nil {
+		// Пустая область ({}) снимает ограничение
+		if req.Region.IsEmpty() {
+			subscriber.Region = nil
+		} else {
+			if err := validateRegion(req.Region); err != nil {
+				return err
+			}
+			subscriber.Region = req.Region
+		}
+	}
+	if req.MinSeverity != nil {
+		severity := strings.TrimSpace(*req.MinSeverity)
+		switch {
+		case severity == "":
+			subscriber.MinSeverity = nil
+		case !models.IsValidSeverity(severity):
+			return fmt.Errorf("%w: unknown severity %q", ErrInvalidSubscriber, severity)
+		default:
+			subscriber.MinSeverity = &severity
+		}
+	}
+	if req.Categories != nil {
+		categories := models.StringList{}
+		for _, category := range *req.Categories {
+			category = strings.TrimSpace(category)
+			if !models.IsValidCategory(category) {
+				return fmt.Errorf("%w: unknown category %q", ErrInvalidSubscriber, category)
+			}
+			categories = append(categories, category)
+		}
+		subscriber.Categories = categories
+	}
+	if req.Enabled != nil {
+		subscriber.Enabled = *req.Enabled
+	}
+	if req.DeliveryMode != nil {
+		mode := strings.TrimSpace(*req.DeliveryMode)
+		if !models.IsValidDeliveryMode(mode) {
+			return fmt.Errorf("%w: unknown delivery mode %q", ErrInvalidSubscriber, mode)
+		}
+		subscriber.DeliveryMode = mode
+	}
+	if req.DigestInterval != nil {
+		interval := strings.TrimSpace(*req.DigestInterval)
+		if _, ok := models.DigestIntervals[interval]; !ok && interval != "" {
+			return fmt.Errorf("%w: unknown digest interval %q", ErrInvalidSubscriber, interval)
+		}
+		subscriber.DigestInterval = nil
+		if interval != "" {
+			subscriber.DigestInterval = &interval
+		}
+	}
+	if subscriber.DeliveryMode == models.DeliveryModeDigest && subscriber.DigestInterval == nil {
+		interval := models.DigestHourly
+		subscriber.DigestInterval = &interval
+	}
+	if req.PayloadTemplate != nil {
+		subscriber.PayloadTemplate = nil
+		if strings.TrimSpace(*req.PayloadTemplate) != "" {
+			subscriber.PayloadTemplate = req.PayloadTemplate
+		}
+	}
+	if req.Envelope != nil {
+		envelope := strings.TrimSpace(*req.Envelope)
+		if !models.IsValidEnvelope(envelope) {
+			return fmt.Errorf("%w: unknown envelope %q", ErrInvalidSubscriber, envelope)
+		}
+		subscriber.Envelope = envelope
+	}
+	if req.Contact != nil {
+		contact := strings.TrimSpace(*req.Contact)
+		if len(contact) > 255 {
+			return fmt.Errorf("%w: contact is longer than 255 characters", ErrInvalidSubscriber)
+		}
+		subscriber.Contact = nil
+		if contact != "" {
+			subscriber.Contact = &contact
+		}
+	}
+	// 0 снимает ограничение
+	if req.MaxRPS != nil {
+		switch rps := *req.MaxRPS; {
+		case rps < 0:
+			return fmt.Errorf("%w: max_rps must not be negative", ErrInvalidSubscriber)
+		case rps == 0:
+			subscriber.MaxRPS = nil
+		default:
+			subscriber.MaxRPS = &rps
+		}
+	}
+	if req.MaxInFlight != nil {
+		switch n := *req.MaxInFlight; {
+		case n < 0:
+			return fmt.Errorf("%w: max_in_flight must not be negative", ErrInvalidSubscriber)
+		case n == 0:
+			subscriber.MaxInFlight = nil
+		default:
+			subscriber.MaxInFlight = &n
+		}
+	}
+	if req.Description != nil {
+		subscriber.Description = req.Description
+	}
+	// Шаблон проверяется и при смене подписки или режима: он должен подходить ко всем событиям
+	return validatePayloadTemplate(subscriber)
+}
+
+func validateRegion(region *models.GeoRegion) error {
+	if b := region.BBox; b != nil {
+		if b.MinLat < -90 || b.MaxLat > 90 || b.MinLng < -180 || b.MaxLng > 180 ||
+			b.MinLat > b.MaxLat || b.MinLng > b.MaxLng {
+			return fmt.Errorf("%w: invalid region bbox", ErrInvalidSubscriber)
+		}
+	}
+	if len(region.Polygon) > 0 {
+		if len(region.Polygon) < 3 {
+			return fmt.Errorf("%w: region polygon needs at least 3 points", ErrInvalidSubscriber)
+		}
+		for _, p := range region.Polygon {
+			if p.Lat < -90 || p.Lat > 90 || p.Lng < -180 || p.Lng > 180 {
+				return fmt.Errorf("%w: invalid region polygon point", ErrInvalidSubscriber)
+			}
+		}
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,112 @@
+// Package webhooktmpl отображает стандартное тело вебхука в формат, ожидаемый
+// получателем, с помощью text/template.
+//
+// Шаблон выполняется над стандартным телом вебхука, разобранным в map
+// (например, {{.event}}, {{.incident.title}}), и должен давать корректный JSON.
+// Значения вставляются функцией json, которая экранирует строки:
+//
+//	{"type": {{json .event}}, "text": {{json .incident.title}}}
+package webhooktmpl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// MaxSize - максимальная длина шаблона в байтах
+	MaxSize = 16 * 1024
+	// MaxOutputSize - максимальный размер результата шаблона: вложенные {{range}}
+	// короткого шаблона могут давать огромный вывод
+	MaxOutputSize = 1024 * 1024
+)
+
+var (
+	ErrTooLarge       = errors.New("webhooktmpl: template is too large")
+	ErrOutputTooLarge = errors.New("webhooktmpl: template output is too large")
+	ErrInvalidJSON    = errors.New("webhooktmpl: template output is not valid JSON")
+)
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse разбирает шаблон. Отсутствующее поле дает nil ({{json .missing}} выводит null),
+// поэтому один шаблон подходит для событий разной формы.
+func Parse(text string) (*Template, error) {
+	if len(text) > MaxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, MaxSize)
+	}
+	tmpl, err := template.New("payload").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Render выполняет шаблон над стандартным телом вебхука payload и возвращает
+// компактный JSON
+func (t *Template) Render(payload []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	// Числа остаются в исходной записи: ID не превращаются в 1.2e+06
+	decoder.UseNumber()
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("webhooktmpl: invalid payload: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := t.tmpl.Execute(&limitedWriter{buf: &out, limit: MaxOutputSize}, data); err != nil {
+		if errors.Is(err, ErrOutputTooLarge) {
+			return nil, fmt.Errorf("%w: limit is %d bytes", ErrOutputTooLarge, MaxOutputSize)
+		}
+		return nil, err
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, out.Bytes()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	return compact.Bytes(), nil
+}
+
+// Render разбирает и выполняет шаблон text над payload
+func Render(text string, payload []byte) ([]byte, error) {
+	t, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return t.Render(payload)
+}
+
+// limitedWriter прерывает выполнение шаблона, как только вывод превысит limit
+type limitedWriter struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, ErrOutputTooLarge
+	}
+	return w.buf.Write(p)
+}
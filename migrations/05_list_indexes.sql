@@ -0,0 +1,7 @@
+CREATE INDEX IF NOT EXISTS idx_incidents_created_at_id ON incidents (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_incidents_updated_at_id ON incidents (updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_incidents_title_id ON incidents (title, id);
+
+CREATE INDEX IF NOT EXISTS idx_location_checks_checked_at_id ON location_checks (checked_at, id);
+CREATE INDEX IF NOT EXISTS idx_location_checks_user_checked_at ON location_checks (user_id, checked_at, id);
+CREATE INDEX IF NOT EXISTS idx_location_checks_user_id_id ON location_checks (user_id, id);
@@ -0,0 +1,16 @@
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS language VARCHAR(8) NOT NULL DEFAULT 'ru';
+
+CREATE TABLE IF NOT EXISTS incident_translations (
+    id SERIAL PRIMARY KEY,
+    incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    language VARCHAR(8) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (incident_id, language)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_translations_incident ON incident_translations (incident_id);
+
+ALTER TABLE webhook_tasks ADD COLUMN IF NOT EXISTS language VARCHAR(8) NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS incident_updates (
+    id SERIAL PRIMARY KEY,
+    incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    author VARCHAR(255),
+    notify BOOLEAN NOT NULL DEFAULT FALSE,
+    notified_users INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_updates_incident ON incident_updates (incident_id, created_at);
+
+ALTER TABLE webhook_tasks ADD COLUMN IF NOT EXISTS event VARCHAR(100) NOT NULL DEFAULT 'user_near_incident';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_tasks_incident_event ON webhook_tasks (incident_id, event, status);
@@ -0,0 +1,39 @@
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS category VARCHAR(50) NOT NULL DEFAULT 'other';
+
+CREATE INDEX IF NOT EXISTS idx_incidents_category ON incidents (category);
+
+CREATE TABLE IF NOT EXISTS report_clusters (
+    id SERIAL PRIMARY KEY,
+    category VARCHAR(50) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    report_count INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    incident_id INTEGER REFERENCES incidents(id) ON DELETE SET NULL,
+    moderator VARCHAR(255),
+    reason TEXT,
+    first_report_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_report_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    moderated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_clusters_pending ON report_clusters (status, category, last_report_at);
+
+CREATE TABLE IF NOT EXISTS incident_reports (
+    id SERIAL PRIMARY KEY,
+    cluster_id INTEGER NOT NULL REFERENCES report_clusters(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    text TEXT NOT NULL,
+    photo_key VARCHAR(512),
+    photo_type VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    incident_id INTEGER REFERENCES incidents(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    moderated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_reports_cluster ON incident_reports (cluster_id);
+CREATE INDEX IF NOT EXISTS idx_incident_reports_user ON incident_reports (user_id, created_at);
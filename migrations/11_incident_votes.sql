@@ -0,0 +1,18 @@
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS confidence DOUBLE PRECISION;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS needs_review BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_needs_review ON incidents (needs_review) WHERE needs_review;
+
+CREATE TABLE IF NOT EXISTS incident_votes (
+    id SERIAL PRIMARY KEY,
+    incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    vote VARCHAR(20) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    distance_meters DOUBLE PRECISION NOT NULL,
+    weight DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (incident_id, user_id)
+);
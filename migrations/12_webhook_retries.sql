@@ -0,0 +1,3 @@
+ALTER TABLE webhook_tasks ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_tasks_pending_next_attempt ON webhook_tasks (next_attempt, id) WHERE status = 'pending';
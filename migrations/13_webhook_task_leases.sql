@@ -0,0 +1,4 @@
+ALTER TABLE webhook_tasks ADD COLUMN IF NOT EXISTS locked_by VARCHAR(255);
+ALTER TABLE webhook_tasks ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_tasks_processing_locked_until ON webhook_tasks (locked_until) WHERE status = 'processing';
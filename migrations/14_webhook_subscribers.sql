@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS webhook_subscribers (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    previous_secret TEXT,
+    event_types JSONB NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE webhook_tasks ADD COLUMN IF NOT EXISTS subscriber_id INTEGER REFERENCES webhook_subscribers(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_tasks_subscriber_id ON webhook_tasks (subscriber_id);
@@ -0,0 +1,7 @@
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS severity VARCHAR(16) NOT NULL DEFAULT 'medium';
+
+CREATE INDEX IF NOT EXISTS idx_incidents_severity ON incidents (severity);
+
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS region JSONB;
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS min_severity VARCHAR(16);
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS categories JSONB NOT NULL DEFAULT '[]';
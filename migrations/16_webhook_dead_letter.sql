@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS webhook_task_replays (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES webhook_tasks(id) ON DELETE CASCADE,
+    replayed_by VARCHAR(255),
+    previous_attempts INTEGER NOT NULL,
+    previous_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_task_replays_task_id ON webhook_task_replays (task_id);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_tasks_failed_updated_at ON webhook_tasks (updated_at, id) WHERE status = 'failed';
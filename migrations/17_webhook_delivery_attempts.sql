@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES webhook_tasks(id) ON DELETE CASCADE,
+    subscriber_id INTEGER,
+    attempt INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    request_headers JSONB,
+    payload_sha256 VARCHAR(64),
+    response_status INTEGER,
+    response_body TEXT,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_task_id ON webhook_delivery_attempts (task_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_subscriber ON webhook_delivery_attempts (subscriber_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_created_at ON webhook_delivery_attempts (created_at);
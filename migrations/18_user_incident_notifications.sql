@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_incident_notifications (
+    user_id VARCHAR(255) NOT NULL,
+    incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) NOT NULL,
+    notified_count INTEGER NOT NULL DEFAULT 1,
+    last_notified_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, incident_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_incident_notifications_incident_id ON user_incident_notifications (incident_id);
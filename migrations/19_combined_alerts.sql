@@ -0,0 +1 @@
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS delivery_mode VARCHAR(16) NOT NULL DEFAULT 'single';
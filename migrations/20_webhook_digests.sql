@@ -0,0 +1,17 @@
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS digest_interval VARCHAR(16);
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS last_digest_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS webhook_digest_entries (
+    id BIGSERIAL PRIMARY KEY,
+    subscriber_id INTEGER NOT NULL REFERENCES webhook_subscribers(id) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    user_id VARCHAR(255),
+    language VARCHAR(8),
+    payload JSONB,
+    digest_task_id INTEGER REFERENCES webhook_tasks(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_digest_entries_pending ON webhook_digest_entries (subscriber_id, created_at) WHERE digest_task_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_digest_entries_task ON webhook_digest_entries (digest_task_id);
@@ -0,0 +1,4 @@
+-- Задачи и записи сводок о событиях инцидента переживают его удаление:
+-- вебхук incident.deleted отправляется уже после удаления, с копией инцидента в payload
+ALTER TABLE webhook_tasks DROP CONSTRAINT IF EXISTS webhook_tasks_incident_id_fkey;
+ALTER TABLE webhook_digest_entries DROP CONSTRAINT IF EXISTS webhook_digest_entries_incident_id_fkey;
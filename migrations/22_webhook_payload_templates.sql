@@ -0,0 +1 @@
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS payload_template TEXT;
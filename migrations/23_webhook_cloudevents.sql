@@ -0,0 +1 @@
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS envelope VARCHAR(32) NOT NULL DEFAULT 'none';
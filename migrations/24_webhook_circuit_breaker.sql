@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoint_circuits (
+    subscriber_id INTEGER PRIMARY KEY REFERENCES webhook_subscribers(id) ON DELETE CASCADE,
+    state VARCHAR(16) NOT NULL DEFAULT 'closed',
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    trips INTEGER NOT NULL DEFAULT 0,
+    failing_since TIMESTAMPTZ,
+    opened_at TIMESTAMPTZ,
+    retry_at TIMESTAMPTZ,
+    last_error TEXT,
+    disabled_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoint_circuits_paused ON webhook_endpoint_circuits (retry_at) WHERE state <> 'closed';
+
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS contact VARCHAR(255);
@@ -0,0 +1,12 @@
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS max_rps DOUBLE PRECISION;
+ALTER TABLE webhook_subscribers ADD COLUMN IF NOT EXISTS max_in_flight INTEGER;
+
+-- Общий для всех реплик token bucket получателя: tokens на момент refilled_at,
+-- отрицательное значение - отправки, уже зарезервированные на будущее
+CREATE TABLE IF NOT EXISTS webhook_rate_buckets (
+    subscriber_id INTEGER PRIMARY KEY REFERENCES webhook_subscribers(id) ON DELETE CASCADE,
+    tokens DOUBLE PRECISION NOT NULL,
+    refilled_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_tasks_in_flight ON webhook_tasks (subscriber_id) WHERE status = 'processing';
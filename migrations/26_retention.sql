@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id SERIAL PRIMARY KEY,
+    table_name VARCHAR(64) NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    affected_rows BIGINT NOT NULL DEFAULT 0,
+    batches INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_runs_table_started ON retention_runs (table_name, started_at);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_tasks_updated_at_id ON webhook_tasks (updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_created_at_id ON webhook_delivery_attempts (created_at, id);
@@ -0,0 +1,4 @@
+-- Подписи больше не сохраняются в журнале доставки, удаляем уже записанные
+UPDATE webhook_delivery_attempts
+SET request_headers = request_headers - 'X-Geowarns-Signature'
+WHERE request_headers -> 'X-Geowarns-Signature' IS NOT NULL;
@@ -0,0 +1,2 @@
+-- SHA-256 токена, по которому автор читает свое сообщение
+ALTER TABLE incident_reports ADD COLUMN IF NOT EXISTS access_token_hash VARCHAR(64);
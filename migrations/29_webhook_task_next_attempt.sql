@@ -0,0 +1,2 @@
+-- Задачи оповещений о близких инцидентах создавались без next_attempt (0001-01-01)
+UPDATE webhook_tasks SET next_attempt = created_at WHERE next_attempt < created_at - INTERVAL '1 day' AND attempts = 0;
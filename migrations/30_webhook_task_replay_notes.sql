@@ -0,0 +1,2 @@
+-- Исполнитель повтора берется из токена, комментарий клиента хранится отдельно
+ALTER TABLE webhook_task_replays ADD COLUMN IF NOT EXISTS note TEXT;
@@ -28,6 +28,110 @@ func MigrateWebhookTask(db *gorm.DB) error {
 	return runMigration(db, "04_webhook_tasks.sql")
 }
 
+func MigrateListIndexes(db *gorm.DB) error {
+	return runMigration(db, "05_list_indexes.sql")
+}
+
+func MigrateIncidentSearch(db *gorm.DB) error {
+	return runMigration(db, "06_incident_search.sql")
+}
+
+func MigrateIncidentTranslations(db *gorm.DB) error {
+	return runMigration(db, "07_incident_translations.sql")
+}
+
+func MigrateIncidentUpdates(db *gorm.DB) error {
+	return runMigration(db, "08_incident_updates.sql")
+}
+
+func MigrateIncidentAttachments(db *gorm.DB) error {
+	return runMigration(db, "09_incident_attachments.sql")
+}
+
+func MigrateIncidentReports(db *gorm.DB) error {
+	return runMigration(db, "10_incident_reports.sql")
+}
+
+func MigrateIncidentVotes(db *gorm.DB) error {
+	return runMigration(db, "11_incident_votes.sql")
+}
+
+func MigrateWebhookRetries(db *gorm.DB) error {
+	return runMigration(db, "12_webhook_retries.sql")
+}
+
+func MigrateWebhookTaskLeases(db *gorm.DB) error {
+	return runMigration(db, "13_webhook_task_leases.sql")
+}
+
+func MigrateWebhookSubscribers(db *gorm.DB) error {
+	return runMigration(db, "14_webhook_subscribers.sql")
+}
+
+func MigrateWebhookSubscriptionFilters(db *gorm.DB) error {
+	return runMigration(db, "15_webhook_subscription_filters.sql")
+}
+
+func MigrateWebhookDeadLetter(db *gorm.DB) error {
+	return runMigration(db, "16_webhook_dead_letter.sql")
+}
+
+func MigrateWebhookDeliveryAttempts(db *gorm.DB) error {
+	return runMigration(db, "17_webhook_delivery_attempts.sql")
+}
+
+func MigrateUserIncidentNotifications(db *gorm.DB) error {
+	return runMigration(db, "18_user_incident_notifications.sql")
+}
+
+func MigrateCombinedAlerts(db *gorm.DB) error {
+	return runMigration(db, "19_combined_alerts.sql")
+}
+
+func MigrateWebhookDigests(db *gorm.DB) error {
+	return runMigration(db, "20_webhook_digests.sql")
+}
+
+func MigrateIncidentLifecycleEvents(db *gorm.DB) error {
+	return runMigration(db, "21_incident_lifecycle_events.sql")
+}
+
+func MigrateWebhookPayloadTemplates(db *gorm.DB) error {
+	return runMigration(db, "22_webhook_payload_templates.sql")
+}
+
+func MigrateWebhookCloudEvents(db *gorm.DB) error {
+	return runMigration(db, "23_webhook_cloudevents.sql")
+}
+
+func MigrateWebhookCircuitBreaker(db *gorm.DB) error {
+	return runMigration(db, "24_webhook_circuit_breaker.sql")
+}
+
+func MigrateWebhookRateLimits(db *gorm.DB) error {
+	return runMigration(db, "25_webhook_rate_limits.sql")
+}
+
+func MigrateRetention(db *gorm.DB) error {
+	return runMigration(db, "26_retention.sql")
+}
+
+func MigrateDeliveryLogSignatures(db *gorm.DB) error {
+	return runMigration(db, "27_delivery_log_signatures.sql")
+}
+
+func MigrateReportAccessTokens(db *gorm.DB) error {
+	return runMigration(db, "28_report_access_tokens.sql")
+}
+
+func MigrateWebhookTaskNextAttempt(db *gorm.DB) error {
+	return runMigration(db, "29_webhook_task_next_attempt.sql")
+}
+
+func MigrateWebhookTaskReplayNotes(db *gorm.DB) error {
+	return runMigration(db, "30_webhook_task_replay_notes.sql")
+}
+
 func runMigration(db *gorm.DB, filename string) error {
 	data, err := migrationsFS.ReadFile(filename)
 	if err != nil {
@@ -59,6 +163,32 @@ func MigrateAll(db *gorm.DB) error {
 		{"location_checks", MigrateLocationCheck},
 		{"incident_stats", MigrateIncidentStat},
 		{"webhook_tasks", MigrateWebhookTask},
+		{"list_indexes", MigrateListIndexes},
+		{"incident_search", MigrateIncidentSearch},
+		{"incident_translations", MigrateIncidentTranslations},
+		{"incident_updates", MigrateIncidentUpdates},
+		{"incident_attachments", MigrateIncidentAttachments},
+		{"incident_reports", MigrateIncidentReports},
+		{"incident_votes", MigrateIncidentVotes},
+		{"webhook_retries", MigrateWebhookRetries},
+		{"webhook_task_leases", MigrateWebhookTaskLeases},
+		{"webhook_subscribers", MigrateWebhookSubscribers},
+		{"webhook_subscription_filters", MigrateWebhookSubscriptionFilters},
+		{"webhook_dead_letter", MigrateWebhookDeadLetter},
+		{"webhook_delivery_attempts", MigrateWebhookDeliveryAttempts},
+		{"user_incident_notifications", MigrateUserIncidentNotifications},
+		{"combined_alerts", MigrateCombinedAlerts},
+		{"webhook_digests", MigrateWebhookDigests},
+		{"incident_lifecycle_events", MigrateIncidentLifecycleEvents},
+		{"webhook_payload_templates", MigrateWebhookPayloadTemplates},
+		{"webhook_cloudevents", MigrateWebhookCloudEvents},
+		{"webhook_circuit_breaker", MigrateWebhookCircuitBreaker},
+		{"webhook_rate_limits", MigrateWebhookRateLimits},
+		{"retention", MigrateRetention},
+		{"delivery_log_signatures", MigrateDeliveryLogSignatures},
+		{"report_access_tokens", MigrateReportAccessTokens},
+		{"webhook_task_next_attempt", MigrateWebhookTaskNextAttempt},
+		{"webhook_task_replay_notes", MigrateWebhookTaskReplayNotes},
 	}
 
 	var errs []error
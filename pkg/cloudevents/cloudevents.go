@@ -0,0 +1,169 @@
+// Package cloudevents реализует конверт CloudEvents 1.0 для HTTP в структурированном
+// и бинарном режимах, без зависимости от SDK.
+//
+// В структурированном режиме событие целиком передается в теле запроса с
+// Content-Type: application/cloudevents+json. В бинарном режиме атрибуты события
+// передаются заголовками ce-*, а тело запроса - это данные события.
+package cloudevents
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	SpecVersion = "1.0"
+
+	// ContentTypeStructured - Content-Type события в структурированном режиме
+	ContentTypeStructured = "application/cloudevents+json"
+
+	// ModeStructured и ModeBinary - режимы передачи события по HTTP
+	ModeStructured = "structured"
+	ModeBinary     = "binary"
+
+	headerPrefix = "ce-"
+)
+
+var (
+	ErrNotCloudEvent      = errors.New("cloudevents: request is not a CloudEvent")
+	ErrUnsupportedVersion = errors.New("cloudevents: unsupported specversion")
+	ErrMissingAttribute   = errors.New("cloudevents: missing required attribute")
+)
+
+// Event - событие CloudEvents 1.0 с данными в JSON
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+}
+
+// New создает событие с JSON-данными data
+func New(id, source, eventType, subject string, at time.Time, data []byte) *Event {
+	at = at.UTC()
+	return &Event{
+		SpecVersion:     SpecVersion,
+		ID:              id,
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            &at,
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// Validate проверяет версию и обязательные атрибуты
+func (e *Event) Validate() error {
+	if e.SpecVersion != SpecVersion {
+		return fmt.Errorf("%w: %q", ErrUnsupportedVersion, e.SpecVersion)
+	}
+	switch {
+	case e.ID == "":
+		return fmt.Errorf("%w: id", ErrMissingAttribute)
+	case e.Source == "":
+		return fmt.Errorf("%w: source", ErrMissingAttribute)
+	case e.Type == "":
+		return fmt.Errorf("%w: type", ErrMissingAttribute)
+	}
+	return nil
+}
+
+// Encode возвращает тело запроса и заголовки для режима mode. Content-Type
+// всегда входит в заголовки.
+func (e *Event) Encode(mode string) ([]byte, http.Header, error) {
+	header := make(http.Header)
+
+	if mode == ModeBinary {
+		header.Set(headerPrefix+"specversion", e.SpecVersion)
+		header.Set(headerPrefix+"id", e.ID)
+		header.Set(headerPrefix+"source", e.Source)
+		header.Set(headerPrefix+"type", e.Type)
+		if e.Subject != "" {
+			header.Set(headerPrefix+"subject", e.Subject)
+		}
+		if e.Time != nil {
+			header.Set(headerPrefix+"time", e.Time.Format(time.RFC3339Nano))
+		}
+		contentType := e.DataContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		header.Set("Content-Type", contentType)
+		return e.Data, header, nil
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	header.Set("Content-Type", ContentTypeStructured)
+	return body, header, nil
+}
+
+// Parse читает событие из HTTP-запроса: структурированного (по Content-Type)
+// или бинарного (по заголовку ce-specversion). Для обычного запроса возвращает
+// ErrNotCloudEvent.
+func Parse(contentType string, header func(name string) string, body []byte) (*Event, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if strings.EqualFold(mediaType, ContentTypeStructured) {
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			return nil, fmt.Errorf("cloudevents: invalid structured event: %w", err)
+		}
+		if event.DataBase64 != "" && len(event.Data) == 0 {
+			data, err := base64.StdEncoding.DecodeString(event.DataBase64)
+			if err != nil {
+				return nil, fmt.Errorf("cloudevents: invalid data_base64: %w", err)
+			}
+			event.Data = data
+			event.DataBase64 = ""
+		}
+		if err := event.Validate(); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+
+	version := header(headerPrefix + "specversion")
+	if version == "" {
+		return nil, ErrNotCloudEvent
+	}
+	event := Event{
+		SpecVersion:     version,
+		ID:              header(headerPrefix + "id"),
+		Source:          header(headerPrefix + "source"),
+		Type:            header(headerPrefix + "type"),
+		Subject:         header(headerPrefix + "subject"),
+		DataContentType: contentType,
+		Data:            body,
+	}
+	if raw := header(headerPrefix + "time"); raw != "" {
+		at, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("cloudevents: invalid time %q", raw)
+		}
+		event.Time = &at
+	}
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ParseRequest читает событие из net/http-запроса с уже прочитанным телом body
+func ParseRequest(r *http.Request, body []byte) (*Event, error) {
+	return Parse(r.Header.Get("Content-Type"), r.Header.Get, body)
+}
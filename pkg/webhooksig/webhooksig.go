@@ -0,0 +1,139 @@
+// Package webhooksig подписывает и проверяет вебхуки GeoWarns.
+//
+// Каждый запрос содержит заголовки X-GeoWarns-Timestamp (unix-время в секундах)
+// и X-GeoWarns-Signature со списком подписей вида "v1=<hex>", разделенных запятыми.
+// Подпись - HMAC-SHA256 от строки "<timestamp>.<тело запроса>". Во время смены
+// секрета запрос подписывается и новым, и старым секретом, поэтому получателю
+// достаточно совпадения хотя бы одной подписи.
+package webhooksig
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TimestampHeader = "X-GeoWarns-Timestamp"
+	SignatureHeader = "X-GeoWarns-Signature"
+
+	// Version - префикс подписи в заголовке
+	Version = "v1"
+
+	// DefaultTolerance - допустимое расхождение времени отправки и проверки
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrNoSecrets         = errors.New("webhooksig: no secrets configured")
+	ErrMissingSignature  = errors.New("webhooksig: missing signature")
+	ErrInvalidTimestamp  = errors.New("webhooksig: invalid timestamp")
+	ErrTimestampExpired  = errors.New("webhooksig: timestamp outside tolerance")
+	ErrSignatureMismatch = errors.New("webhooksig: signature mismatch")
+)
+
+// Sign возвращает hex-подпись тела запроса с временем timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Header возвращает значение заголовка подписи: по одной подписи на каждый секрет
+func Header(secrets []string, timestamp int64, body []byte) string {
+	parts := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		parts = append(parts, Version+"="+Sign(secret, timestamp, body))
+	}
+	return strings.Join(parts, ",")
+}
+
+// SignRequest выставляет заголовки времени и подписи для запроса с телом body
+func SignRequest(req *http.Request, secrets []string, body []byte, now time.Time) {
+	timestamp := now.Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Header(secrets, timestamp, body))
+}
+
+// Verify проверяет подпись signatureHeader для тела body. Подпись считается верной,
+// если она совпадает для любого из secrets, а timestamp отличается от now не больше
+// чем на tolerance (при tolerance <= 0 время не проверяется).
+func Verify(signatureHeader, timestamp string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	if len(secrets) == 0 {
+		return ErrNoSecrets
+	}
+	if signatureHeader == "" {
+		return ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if tolerance > 0 {
+		diff := now.Sub(time.Unix(ts, 0))
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > tolerance {
+			return fmt.Errorf("%w: %s", ErrTimestampExpired, diff.Round(time.Second))
+		}
+	}
+
+	var received [][]byte
+	for _, part := range strings.Split(signatureHeader, ",") {
+		version, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || version != Version {
+			continue
+		}
+		if sig, err := hex.DecodeString(value); err == nil {
+			received = append(received, sig)
+		}
+	}
+	if len(received) == 0 {
+		return ErrMissingSignature
+	}
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		expected, _ := hex.DecodeString(Sign(secret, ts, body))
+		for _, sig := range received {
+			if hmac.Equal(sig, expected) {
+				return nil
+			}
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// VerifyRequest читает тело запроса, проверяет подпись и возвращает тело.
+// r.Body заменяется копией, чтобы его можно было прочитать повторно.
+func VerifyRequest(r *http.Request, secrets []string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = Verify(r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), body, secrets, tolerance, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
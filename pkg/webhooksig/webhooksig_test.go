@@ -0,0 +1,158 @@
+package webhooksig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	testTimestamp = int64(1700000000)
+
+	// HMAC-SHA256 от "<timestamp>.<body>", посчитанные независимо от пакета
+	sigCurrent  = "79b16c99a9ecdf8abe40575e671b36a9522efc6e4b068c6e08a39917c73213b3"
+	sigPrevious = "aa88dcbbf061b9aa4053b1a7bf8bdb434b68832bb52b20b3b93c74482df92c0e"
+)
+
+var testBody = []byte(`{"event":"incident.created"}`)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		want      string
+	}{
+		{"current secret", "whsec_test", testTimestamp, testBody, sigCurrent},
+		{"previous secret", "other", testTimestamp, testBody, sigPrevious},
+		{"empty body", "whsec_test", testTimestamp, nil, "5967f3c560522fa40cf2876ebc3c3a08551dd6959aaade3b413460591895bdcc"},
+		{"timestamp is signed", "whsec_test", testTimestamp + 1, testBody, "e5f683fac8800b5d0492ce16e996a4e3118192d954a38b15bcc1e4904cf41b55"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, tt.body); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		want    string
+	}{
+		{"one secret", []string{"whsec_test"}, "v1=" + sigCurrent},
+		{"rotation signs with both in order", []string{"whsec_test", "other"}, "v1=" + sigCurrent + ",v1=" + sigPrevious},
+		{"empty secrets are skipped", []string{"", "whsec_test", ""}, "v1=" + sigCurrent},
+		{"no secrets", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Header(tt.secrets, testTimestamp, testBody); got != tt.want {
+				t.Errorf("Header() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(testTimestamp, 0)
+	ts := strconv.FormatInt(testTimestamp, 10)
+	both := "v1=" + sigCurrent + ",v1=" + sigPrevious
+
+	tests := []struct {
+		name      string
+		header    string
+		timestamp string
+		body      []byte
+		secrets   []string
+		tolerance time.Duration
+		now       time.Time
+		wantErr   error
+	}{
+		{name: "valid", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}},
+		{name: "rotation: receiver knows new secret", header: both, timestamp: ts, secrets: []string{"whsec_test"}},
+		{name: "rotation: receiver knows old secret", header: both, timestamp: ts, secrets: []string{"other"}},
+		{name: "rotation: sender signs with old secret only", header: "v1=" + sigPrevious, timestamp: ts, secrets: []string{"whsec_test", "other"}},
+		{name: "spaces around parts", header: " v1=deadbeef , v1=" + sigCurrent + " ", timestamp: ts, secrets: []string{"whsec_test"}},
+		{name: "unknown versions are ignored", header: "v0=" + sigCurrent + ",v1=" + sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}},
+		{name: "empty receiver secret is ignored", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{"", "whsec_test"}},
+
+		{name: "wrong secret", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{"nope"}, wantErr: ErrSignatureMismatch},
+		{name: "tampered body", header: "v1=" + sigCurrent, timestamp: ts, body: []byte(`{"event":"incident.deleted"}`), secrets: []string{"whsec_test"}, wantErr: ErrSignatureMismatch},
+		{name: "timestamp replaced", header: "v1=" + sigCurrent, timestamp: strconv.FormatInt(testTimestamp+1, 10), now: time.Unix(testTimestamp+1, 0), secrets: []string{"whsec_test"}, wantErr: ErrSignatureMismatch},
+		{name: "v1 with empty value", header: "v1=", timestamp: ts, secrets: []string{"whsec_test"}, wantErr: ErrSignatureMismatch},
+		{name: "only empty secrets", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{""}, wantErr: ErrSignatureMismatch},
+
+		{name: "no secrets", header: "v1=" + sigCurrent, timestamp: ts, wantErr: ErrNoSecrets},
+		{name: "missing header", header: "", timestamp: ts, secrets: []string{"whsec_test"}, wantErr: ErrMissingSignature},
+		{name: "no version prefix", header: sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}, wantErr: ErrMissingSignature},
+		{name: "unknown version only", header: "v2=" + sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}, wantErr: ErrMissingSignature},
+		{name: "not hex", header: "v1=zz", timestamp: ts, secrets: []string{"whsec_test"}, wantErr: ErrMissingSignature},
+		{name: "only separators", header: ",,", timestamp: ts, secrets: []string{"whsec_test"}, wantErr: ErrMissingSignature},
+		{name: "missing timestamp", header: "v1=" + sigCurrent, timestamp: "", secrets: []string{"whsec_test"}, wantErr: ErrInvalidTimestamp},
+		{name: "non-numeric timestamp", header: "v1=" + sigCurrent, timestamp: "yesterday", secrets: []string{"whsec_test"}, wantErr: ErrInvalidTimestamp},
+
+		{name: "at tolerance in the past", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}, tolerance: DefaultTolerance, now: now.Add(DefaultTolerance)},
+		{name: "at tolerance in the future", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}, tolerance: DefaultTolerance, now: now.Add(-DefaultTolerance)},
+		{name: "beyond tolerance in the past", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}, tolerance: DefaultTolerance, now: now.Add(DefaultTolerance + time.Second), wantErr: ErrTimestampExpired},
+		{name: "beyond tolerance in the future", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}, tolerance: DefaultTolerance, now: now.Add(-DefaultTolerance - time.Second), wantErr: ErrTimestampExpired},
+		{name: "zero tolerance skips time check", header: "v1=" + sigCurrent, timestamp: ts, secrets: []string{"whsec_test"}, now: now.Add(24 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			if body == nil {
+				body = testBody
+			}
+			checkedAt := tt.now
+			if checkedAt.IsZero() {
+				checkedAt = now
+			}
+
+			err := Verify(tt.header, tt.timestamp, body, tt.secrets, tt.tolerance, checkedAt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	secrets := []string{"whsec_new", "whsec_old"}
+
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/webhook", bytes.NewReader(testBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SignRequest(req, secrets, testBody, time.Now())
+
+	// Получатель, у которого остался только старый секрет, тоже принимает запрос
+	body, err := VerifyRequest(req, secrets[1:], DefaultTolerance)
+	if err != nil {
+		t.Fatalf("VerifyRequest() error = %v", err)
+	}
+	if !bytes.Equal(body, testBody) {
+		t.Errorf("VerifyRequest() body = %s, want %s", body, testBody)
+	}
+
+	// Тело можно прочитать повторно
+	again, err := io.ReadAll(req.Body)
+	if err != nil || !bytes.Equal(again, testBody) {
+		t.Errorf("request body after VerifyRequest = %s, %v", again, err)
+	}
+
+	if _, err := VerifyRequest(req, []string{"whsec_other"}, DefaultTolerance); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("VerifyRequest() with unknown secret error = %v, want %v", err, ErrSignatureMismatch)
+	}
+}